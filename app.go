@@ -27,9 +27,12 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
+	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -55,12 +58,26 @@ const (
 
 	GrabberAnimSpeed = 1.4
 	RegionAnimSpeed  = 2.5
+
+	// How far the pointer needs to be dragged while choosing a region to
+	// select freely instead
+	DragThreshold = 5.0
+
+	// How long retrieving the regions or the cursor position may take
+	RegionsTimeout = 2 * time.Second
+)
+
+// Linux input event codes of the keys that walk through the containers
+const (
+	KeyUp   = 103
+	KeyDown = 108
 )
 
 type App struct {
 	start          [2]float64 // The top left corner of the selection box
 	end            [2]float64 // The bottom right corner of the selection box
 	pointer        [2]float64 // The raw position of the pointer in global coordinates
+	pointerKnown   bool       // Whether the position of the pointer is known
 	anchor         [2]float64 // The position where the pointer has been released
 	offset         [2]float64
 	selectedOutput samure.Output
@@ -76,6 +93,9 @@ type App struct {
 	grabberBorderWidth float64
 
 	selectedRegion    Region
+	hoveredRegion     Region   // The region below the pointer
+	regionPressed     bool     // Whether the pointer has been pressed on a region
+	regionStack       []Region // The regions that have been walked up from
 	regionAnim        float64
 	currentRegionAnim [4]float64
 	startRegionAnim   [4]float64
@@ -91,6 +111,13 @@ type App struct {
 	aspect             float64
 	regionsObj         Regions
 	regions            []Region
+	regionsUpdate      chan []Region
+	regionsCancel      context.CancelFunc
+	cursorUpdate       chan [2]int
+	outputsRegions     *OutputsRegions
+	workspaceRegions   *WorkspaceRegions
+	includeRules       []RegionRule
+	excludeRules       []RegionRule
 }
 
 func (a App) GetSelection() (samure.Rect, error) {
@@ -141,28 +168,347 @@ func (a *App) OnUpdate(ctx samure.Context, deltaTime float64) {
 			ctx.SetRenderState(samure.RenderStateOnce)
 		}
 
-		if !flags.FreezeScreen && a.regionsObj != nil {
-			a.regions = a.regionsObj.OutputRegions()
-			a.pointerMove(ctx, a.pointer[0], a.pointer[1], 0.0, 0.0, a.selectedOutput)
+		select {
+		case rs, ok := <-a.regionsUpdate:
+			if ok {
+				a.setRegions(rs)
+				if a.pointerKnown {
+					a.pointerMoveOrSelect(ctx)
+				}
+			} else {
+				a.regionsUpdate = nil
+			}
+		default:
 		}
 	}
+
+	select {
+	case pos, ok := <-a.cursorUpdate:
+		if ok && !a.pointerKnown {
+			a.initPointer(ctx, float64(pos[0]), float64(pos[1]))
+		}
+		a.cursorUpdate = nil
+	default:
+	}
 }
 
-func (a App) createOutputString() (string, error) {
-	// Retrieve data that will be output using the format
-	sel, err := a.GetSelection()
-	if err != nil {
-		return "", err
+// initPointer sets the first known position of the pointer and highlights
+// the region or output below it
+func (a *App) initPointer(ctx samure.Context, px, py float64) {
+	a.pointer[0], a.pointer[1] = px, py
+	a.pointerKnown = true
+
+	switch a.state {
+	case StateChooseRegion:
+		a.pointerMoveOrSelect(ctx)
+	case StateChooseOutput:
+		a.selectedOutput = outputAt(ctx, int(px), int(py))
+		ctx.SetRenderState(samure.RenderStateOnce)
 	}
+}
 
-	outputName := "nil"
-	if a.selectedOutput.Handle != nil {
-		outputName = a.selectedOutput.Name()
+// pointerMoveOrSelect highlights the region below the pointer. The first
+// region appears without an animation.
+func (a *App) pointerMoveOrSelect(ctx samure.Context) {
+	if a.selectedOutput.Handle == nil {
+		a.selectedOutput = outputAt(ctx, int(a.pointer[0]), int(a.pointer[1]))
+	}
+
+	if !isRegionSet(a.selectedRegion.Geo) && !isRegionAnimSet(a.currentRegionAnim) {
+		a.selectRegionAt(int(a.pointer[0]), int(a.pointer[1]))
+		ctx.SetPointerShape(a.getCursorShape())
+		ctx.SetRenderState(samure.RenderStateOnce)
+	} else {
+		a.pointerMove(ctx, a.pointer[0], a.pointer[1], 0.0, 0.0, a.selectedOutput)
+	}
+}
+
+// startRegionsWorker retrieves the regions in the background and hands them
+// to OnUpdate through regionsUpdate. They are retrieved again whenever they
+// have changed or every flags.RegionsRefresh if they can not be watched.
+// While the screen is frozen they are only retrieved once. Errors are only
+// reported when they change and as long as no regions have been retrieved
+// they are retried every flags.RegionsRefresh.
+func (a *App) startRegionsWorker() {
+	regionsObj := a.regionsObj
+	update := make(chan []Region, 1)
+	workerCtx, cancel := context.WithCancel(context.Background())
+	a.regionsUpdate = update
+	a.regionsCancel = cancel
+
+	go func() {
+		defer close(update)
+
+		var changed chan struct{}
+		if watcher, ok := regionsObj.(RegionsWatcher); ok && !flags.FreezeScreen {
+			changed = make(chan struct{}, 1)
+			if err := watcher.Watch(workerCtx, changed); err != nil {
+				if flags.Debug {
+					fmt.Fprintf(os.Stderr, "Could not watch regions: %v\n", err)
+				}
+				changed = nil
+			}
+		}
+
+		// Only hand over regions that differ from the previous ones
+		var last []Region
+		var sent bool
+		var lastErr string
+		send := func() {
+			fetchCtx, cancelFetch := context.WithTimeout(workerCtx, RegionsTimeout)
+			rs, err := regionsObj.OutputRegions(fetchCtx)
+			cancelFetch()
+
+			if err != nil {
+				if workerCtx.Err() != nil {
+					return
+				}
+				if err.Error() != lastErr {
+					lastErr = err.Error()
+					fmt.Fprintf(os.Stderr, "Failed to retrieve regions: %v\n", err)
+				}
+				// Keep the previous regions unless some could be retrieved
+				if len(rs) == 0 {
+					return
+				}
+			} else {
+				lastErr = ""
+			}
+
+			if sent && slices.Equal(rs, last) {
+				return
+			}
+			last, sent = rs, true
+
+			// Replace the previous regions if they have not been used yet
+			select {
+			case <-update:
+			default:
+			}
+			update <- rs
+		}
+
+		ticker := time.NewTicker(flags.RegionsRefresh)
+		defer ticker.Stop()
+
+		// Slow producers like exec or stdin might not deliver their regions
+		// in time, so keep trying until the first ones have arrived
+		for send(); !sent; send() {
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+		if flags.FreezeScreen {
+			return
+		}
+
+		for changed != nil {
+			select {
+			case <-workerCtx.Done():
+				return
+			case _, ok := <-changed:
+				if !ok {
+					// The regions can not be watched anymore, poll them instead
+					changed = nil
+					break
+				}
+				send()
+			}
+		}
+
+		for {
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+				send()
+			}
+		}
+	}()
+}
+
+// stopRegionsWorker stops retrieving the regions and everything that the
+// provider runs in the background, like region helpers
+func (a *App) stopRegionsWorker() {
+	if a.regionsCancel != nil {
+		a.regionsCancel()
+		a.regionsCancel = nil
+
+		if err := closeRegions(a.regionsObj); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to stop retrieving regions: %v\n", err)
+		}
 	}
+}
+
+// fetchCursorPos retrieves the position of the cursor in the background and
+// hands it to OnUpdate through cursorUpdate
+func (a *App) fetchCursorPos() {
+	regionsObj := a.regionsObj
+	cursor := make(chan [2]int, 1)
+	a.cursorUpdate = cursor
+
+	go func() {
+		defer close(cursor)
+
+		if !regionsObj.Capabilities().Has(CapabilityCursor) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), RegionsTimeout)
+		defer cancel()
+
+		x, y, err := regionsObj.CursorPos(ctx)
+		if err != nil {
+			if flags.Debug {
+				fmt.Fprintf(os.Stderr, "Could not retrieve the cursor position: %v\n", err)
+			}
+			return
+		}
+		cursor <- [2]int{x, y}
+	}()
+}
+
+// outputAt returns the output at the given position
+func outputAt(ctx samure.Context, x, y int) samure.Output {
+	for i := 0; i < ctx.LenOutputs(); i++ {
+		if ctx.Output(i).PointInOutput(x, y) {
+			return ctx.Output(i)
+		}
+	}
+	return samure.Output{Handle: nil}
+}
+
+// setRegions sets the regions that can be chosen from after filtering them
+// and applying the padding
+func (a *App) setRegions(rs []Region) {
+	// The outputs that are offered below the other regions are not filtered
+	if flags.Outputs && (len(a.includeRules) != 0 || len(a.excludeRules) != 0) {
+		var windows, outputs []Region
+		for _, r := range rs {
+			if r.Provider == "outputs" {
+				outputs = append(outputs, r)
+			} else {
+				windows = append(windows, r)
+			}
+		}
+		rs = append(filterRegions(windows, a.includeRules, a.excludeRules), outputs...)
+	} else {
+		rs = filterRegions(rs, a.includeRules, a.excludeRules)
+	}
+	if flags.RegionPadding != 0 {
+		rs = padRegions(rs, flags.RegionPadding)
+	}
+
+	// The regions are hit tested from top to bottom
+	rs = slices.Clone(rs)
+	sortRegions(rs)
+	a.regions = rs
+}
+
+// regionAt returns the top most region at the given position or the
+// smallest one if requested. Containers are only selected by walking up
+// from one of their children.
+func (a *App) regionAt(x, y int) (r Region) {
+	for i := range a.regions {
+		if a.regions[i].Container || !a.regions[i].Geo.PointInOutput(x, y) {
+			continue
+		}
+
+		if flags.RegionPick != "smallest" {
+			return a.regions[i]
+		}
+
+		if !isRegionSet(r.Geo) || a.regions[i].Geo.W*a.regions[i].Geo.H < r.Geo.W*r.Geo.H {
+			r = a.regions[i]
+		}
+	}
+	return
+}
+
+// selectRegionAt selects the region at the given position without animating
+func (a *App) selectRegionAt(x, y int) {
+	a.hoveredRegion = a.regionAt(x, y)
+	a.regionStack = nil
+	a.selectedRegion = a.hoveredRegion
 
-	regionName := "nil"
 	if isRegionSet(a.selectedRegion.Geo) {
-		regionName = a.selectedRegion.Name
+		a.currentRegionAnim[0] = float64(a.selectedRegion.Geo.X)
+		a.currentRegionAnim[1] = float64(a.selectedRegion.Geo.Y)
+		a.currentRegionAnim[2] = float64(a.selectedRegion.Geo.X + a.selectedRegion.Geo.W)
+		a.currentRegionAnim[3] = float64(a.selectedRegion.Geo.Y + a.selectedRegion.Geo.H)
+	}
+}
+
+func (a App) outputName() string {
+	if a.selectedOutput.Handle == nil {
+		return "nil"
+	}
+	return a.selectedOutput.Name()
+}
+
+// regionFields are the fields of the selected region that can be inserted
+// into the command as %FIELD%
+var regionFields = []string{"region", "class", "pid", "workspace", "address", "floating", "provider"}
+
+// regionField returns a field of the selected region as a string. Fields
+// that are unknown and all fields if no region is selected are "nil".
+func (a App) regionField(field string) string {
+	r := a.selectedRegion
+	if !isRegionSet(r.Geo) {
+		return "nil"
+	}
+
+	var value string
+	switch field {
+	case "region":
+		value = r.Name
+	case "class":
+		value = r.Class
+	case "pid":
+		if r.Pid != 0 {
+			value = strconv.Itoa(r.Pid)
+		}
+	case "workspace":
+		value = r.Workspace
+	case "address":
+		value = r.Address
+	case "floating":
+		if !r.FloatingKnown {
+			break
+		}
+		if r.Floating {
+			value = "floating"
+		} else {
+			value = "tiled"
+		}
+	case "provider":
+		value = r.Provider
+	}
+
+	// The name of a region may be empty on purpose
+	if value == "" && field != "region" {
+		return "nil"
+	}
+	return value
+}
+
+// commandReplacer replaces %geometry%, %output% and the fields of the
+// selected region in the arguments of the command
+func (a App) commandReplacer(geometry string) *strings.Replacer {
+	oldnew := []string{"%geometry%", geometry, "%output%", a.outputName()}
+	for _, field := range regionFields {
+		oldnew = append(oldnew, "%"+field+"%", a.regionField(field))
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+func (a App) createOutputString() (string, error) {
+	// Retrieve data that will be output using the format
+	sel, err := a.GetSelection()
+	if err != nil {
+		return "", err
 	}
 
 	var outputRelX, outputRelY, outputRelW, outputRelH int
@@ -208,9 +554,21 @@ func (a App) createOutputString() (string, error) {
 			case 'H':
 				out.WriteString(strconv.Itoa(outputRelH))
 			case 'r':
-				out.WriteString(regionName)
+				out.WriteString(a.regionField("region"))
+			case 'c':
+				out.WriteString(a.regionField("class"))
+			case 'p':
+				out.WriteString(a.regionField("pid"))
+			case 'k':
+				out.WriteString(a.regionField("workspace"))
+			case 'a':
+				out.WriteString(a.regionField("address"))
+			case 'f':
+				out.WriteString(a.regionField("floating"))
+			case 'P':
+				out.WriteString(a.regionField("provider"))
 			case 'o':
-				out.WriteString(outputName)
+				out.WriteString(a.outputName())
 			case '%':
 				out.WriteRune(r)
 			default:
@@ -238,13 +596,6 @@ func isRegionAnimSet(r [4]float64) bool {
 	return r[0] != 0 || r[1] != 0 || r[2] != 0 || r[3] != 0
 }
 
-func unsetRegion(r *samure.Rect) {
-	r.X = 0
-	r.Y = 0
-	r.W = 0
-	r.H = 0
-}
-
 func createScreenshotFilename(t time.Time) (string, error) {
 	var out strings.Builder
 	var parseSpecifier bool
@@ -0,0 +1,138 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	samure "github.com/Samudevv/samurai-render-go"
+)
+
+func TestRegionFormat(t *testing.T) {
+	a := App{
+		state: StateChooseRegion,
+		selectedRegion: Region{
+			Geo:           samure.Rect{X: 10, Y: 20, W: 300, H: 200},
+			Name:          "Terminal",
+			Class:         "kitty",
+			Workspace:     "2",
+			Floating:      true,
+			FloatingKnown: true,
+			Provider:      "hyprland",
+			Pid:           1234,
+			Address:       "0x55d0c0a4e8f0",
+		},
+	}
+
+	flags.Format = "%x,%y %wx%h %r|%c|%p|%k|%a|%f|%P|%o"
+	defer func() { flags.Format = "" }()
+
+	out, err := a.createOutputString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "10,20 300x200 Terminal|kitty|1234|2|0x55d0c0a4e8f0|floating|hyprland|nil"; out != expected {
+		t.Errorf("expected \"%s\" but got \"%s\"", expected, out)
+	}
+
+	cmd := a.commandReplacer(out).Replace("hyprctl dispatch closewindow address:%address% %pid% %class% %floating%")
+	if expected := "hyprctl dispatch closewindow address:0x55d0c0a4e8f0 1234 kitty floating"; cmd != expected {
+		t.Errorf("expected \"%s\" but got \"%s\"", expected, cmd)
+	}
+
+	// Fields are nil if they are unknown or no region has been selected
+	a.selectedRegion.Pid = 0
+	if pid := a.regionField("pid"); pid != "nil" {
+		t.Errorf("expected nil but got \"%s\"", pid)
+	}
+	a.selectedRegion.FloatingKnown = false
+	if floating := a.regionField("floating"); floating != "nil" {
+		t.Errorf("expected nil but got \"%s\"", floating)
+	}
+	a.selectedRegion = Region{}
+	if class := a.regionField("class"); class != "nil" {
+		t.Errorf("expected nil but got \"%s\"", class)
+	}
+}
+
+func TestOutputsAndRegions(t *testing.T) {
+	saved := flags
+	defer func() { flags = saved }()
+
+	a, err := CreateApp([]string{"smel", "-p", "-r", "arg", "-R", "0,0 100x100 window"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.state != StateChooseRegion {
+		t.Fatalf("expected to choose regions but the state is %d", a.state)
+	}
+	if a.outputsRegions == nil {
+		t.Fatal("the outputs have not been added to the regions")
+	}
+
+	a.outputsRegions.regions = []Region{
+		{Geo: samure.Rect{W: 1920, H: 1080}, Name: "DP-1", Provider: "outputs"},
+	}
+	rs, err := a.regionsObj.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.setRegions(rs)
+
+	// The outputs are only chosen where there is no other region
+	if r := a.regionAt(50, 50); r.Name != "window" {
+		t.Errorf("expected the window but got \"%s\"", r.Name)
+	}
+	if r := a.regionAt(500, 500); r.Name != "DP-1" || r.Provider != "outputs" {
+		t.Errorf("expected the output but got \"%s\"", r.Name)
+	}
+}
+
+func TestOutputsAndRegionsFiltered(t *testing.T) {
+	saved := flags
+	defer func() { flags = saved }()
+
+	a, err := CreateApp([]string{"smel", "-p", "-r", "arg", "-R", "0,0 100x100 window", "--include", "title=other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.outputsRegions.regions = []Region{
+		{Geo: samure.Rect{W: 1920, H: 1080}, Name: "DP-1", Provider: "outputs"},
+	}
+	rs, err := a.regionsObj.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.setRegions(rs)
+
+	// The rules only apply to the regions and not to the outputs below them
+	if r := a.regionAt(50, 50); r.Name != "DP-1" || r.Provider != "outputs" {
+		t.Errorf("expected the output but got \"%s\"", r.Name)
+	}
+}
@@ -0,0 +1,183 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+
+	samure "github.com/Samudevv/samurai-render-go"
+)
+
+// UsableRegions uses the area of every output that is not covered by bars
+// and other exclusive zones as regions
+type UsableRegions struct {
+	provider Regions
+	area     UsableAreaProvider
+}
+
+func (u *UsableRegions) OutputRegions(ctx context.Context) ([]Region, error) {
+	rs, err := u.area.UsableAreas(ctx)
+	for i := range rs {
+		rs[i].Provider = "usable"
+	}
+	stackRegions(rs)
+	return rs, err
+}
+
+func (u *UsableRegions) CursorPos(ctx context.Context) (int, int, error) {
+	return u.provider.CursorPos(ctx)
+}
+
+func (u *UsableRegions) Capabilities() RegionsCapabilities {
+	return u.provider.Capabilities() & (CapabilityCursor | CapabilityWatch | CapabilityWorkspace)
+}
+
+func (u *UsableRegions) Close() error {
+	return closeRegions(u.provider)
+}
+
+func (u *UsableRegions) Watch(ctx context.Context, changed chan<- struct{}) error {
+	return watchProvider(ctx, u.provider, changed)
+}
+
+// WorkspaceRegions uses the bounding box of all windows on the current
+// workspaces as regions. Since workspaces on different outputs can have the
+// same name the windows are also grouped by their output. The outputs are
+// not known until the wayland context has been created and are set using
+// SetOutputs before any regions are retrieved.
+type WorkspaceRegions struct {
+	provider Regions
+	outputs  []samure.Rect
+}
+
+func (w *WorkspaceRegions) SetOutputs(ctx samure.Context) {
+	w.outputs = nil
+	for i := 0; i < ctx.LenOutputs(); i++ {
+		w.outputs = append(w.outputs, ctx.Output(i).Geo())
+	}
+}
+
+// OutputRegions returns one region per workspace and output in the order in
+// which the workspaces appear in the regions of the provider
+func (w *WorkspaceRegions) OutputRegions(ctx context.Context) (rs []Region, err error) {
+	windows, err := w.provider.OutputRegions(ctx)
+
+	type workspaceKey struct {
+		output    int
+		workspace string
+	}
+
+	index := make(map[workspaceKey]int)
+	var outputs []int
+	for _, r := range windows {
+		// Containers are already covered by their windows and layer
+		// surfaces do not belong to a workspace
+		if r.Container || r.Workspace == "" {
+			continue
+		}
+
+		key := workspaceKey{output: w.outputAt(r.Geo), workspace: r.Workspace}
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(rs)
+			outputs = append(outputs, key.output)
+			rs = append(rs, Region{
+				Geo:       r.Geo,
+				Name:      r.Workspace,
+				Workspace: r.Workspace,
+				Provider:  "workspace",
+			})
+			continue
+		}
+
+		rs[i].Geo = boundingBox(rs[i].Geo, r.Geo)
+	}
+
+	// Windows that hang over the edge of their output do not grow the
+	// workspace onto the neighbouring one
+	for i, o := range outputs {
+		if o == -1 {
+			continue
+		}
+		if geo, ok := intersectRects(rs[i].Geo, w.outputs[o]); ok {
+			rs[i].Geo = geo
+		}
+	}
+
+	stackRegions(rs)
+	return
+}
+
+// outputAt returns the index of the output that contains the center of geo
+// or -1 if it is on none of them
+func (w *WorkspaceRegions) outputAt(geo samure.Rect) int {
+	x, y := geo.X+geo.W/2, geo.Y+geo.H/2
+	for i, o := range w.outputs {
+		if o.PointInOutput(x, y) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (w *WorkspaceRegions) CursorPos(ctx context.Context) (int, int, error) {
+	return w.provider.CursorPos(ctx)
+}
+
+func (w *WorkspaceRegions) Capabilities() RegionsCapabilities {
+	return w.provider.Capabilities() & (CapabilityCursor | CapabilityWatch | CapabilityWorkspace)
+}
+
+func (w *WorkspaceRegions) Close() error {
+	return closeRegions(w.provider)
+}
+
+func (w *WorkspaceRegions) Watch(ctx context.Context, changed chan<- struct{}) error {
+	return watchProvider(ctx, w.provider, changed)
+}
+
+// watchProvider watches the regions of provider if it supports it
+func watchProvider(ctx context.Context, provider Regions, changed chan<- struct{}) error {
+	watcher, ok := provider.(RegionsWatcher)
+	if !ok {
+		return errors.New("the regions can not be watched")
+	}
+	return watcher.Watch(ctx, changed)
+}
+
+// boundingBox returns the smallest rectangle that contains a and b
+func boundingBox(a, b samure.Rect) samure.Rect {
+	x := min(a.X, b.X)
+	y := min(a.Y, b.Y)
+	return samure.Rect{
+		X: x,
+		Y: y,
+		W: max(a.X+a.W, b.X+b.W) - x,
+		H: max(a.Y+a.H, b.Y+b.H) - y,
+	}
+}
@@ -0,0 +1,87 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	samure "github.com/Samudevv/samurai-render-go"
+)
+
+func TestWorkspaceRegions(t *testing.T) {
+	w := WorkspaceRegions{provider: &ArgumentRegions{regions: []Region{
+		{Geo: samure.Rect{X: 100, Y: 100, W: 200, H: 200}, Workspace: "1"},
+		{Geo: samure.Rect{X: 2000, Y: 50, W: 500, H: 500}, Workspace: "2"},
+		{Geo: samure.Rect{X: 400, Y: 50, W: 100, H: 600}, Workspace: "1"},
+		{Geo: samure.Rect{W: 1920, H: 1080}, Workspace: "1", Container: true},
+		{Geo: samure.Rect{W: 1920, H: 30}, Name: "waybar"},
+	}}}
+
+	rs, err := w.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Region{
+		{Geo: samure.Rect{X: 100, Y: 50, W: 400, H: 600}, Name: "1", Workspace: "1", Provider: "workspace", Z: 2},
+		{Geo: samure.Rect{X: 2000, Y: 50, W: 500, H: 500}, Name: "2", Workspace: "2", Provider: "workspace", Z: 1},
+	}
+	if !slices.Equal(rs, expected) {
+		t.Errorf("expected %v but got %v", expected, rs)
+	}
+}
+
+func TestWorkspaceRegionsOutputs(t *testing.T) {
+	w := WorkspaceRegions{
+		provider: &ArgumentRegions{regions: []Region{
+			{Geo: samure.Rect{X: 100, Y: 100, W: 200, H: 200}, Workspace: "1"},
+			{Geo: samure.Rect{X: 2000, Y: 100, W: 200, H: 200}, Workspace: "1"},
+			{Geo: samure.Rect{X: 1800, Y: 500, W: 400, H: 100}, Workspace: "1"},
+		}},
+		outputs: []samure.Rect{
+			{W: 1920, H: 1080},
+			{X: 1920, W: 1920, H: 1080},
+		},
+	}
+
+	rs, err := w.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Workspaces with the same name on different outputs are different
+	// regions, which do not reach onto the other output
+	expected := []Region{
+		{Geo: samure.Rect{X: 100, Y: 100, W: 200, H: 200}, Name: "1", Workspace: "1", Provider: "workspace", Z: 2},
+		{Geo: samure.Rect{X: 1920, Y: 100, W: 280, H: 500}, Name: "1", Workspace: "1", Provider: "workspace", Z: 1},
+	}
+	if !slices.Equal(rs, expected) {
+		t.Errorf("expected %v but got %v", expected, rs)
+	}
+}
@@ -0,0 +1,231 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	samure "github.com/Samudevv/samurai-render-go"
+)
+
+var (
+	argumentPositionRegex = regexp.MustCompile(`^([+-]?\d+),([+-]?\d+)$`)
+	argumentSizeRegex     = regexp.MustCompile(`^([+-]?\d+)x([+-]?\d+)$`)
+)
+
+// ArgumentRegions uses the regions declared with --regions-arg
+type ArgumentRegions struct {
+	regions []Region
+}
+
+func (a *ArgumentRegions) OutputRegions(context.Context) ([]Region, error) {
+	return a.regions, nil
+}
+
+func (*ArgumentRegions) CursorPos(context.Context) (int, int, error) {
+	return 0, 0, ErrNotSupported
+}
+
+func (*ArgumentRegions) Capabilities() RegionsCapabilities {
+	return CapabilityWatch
+}
+
+// The regions from the argument never change, so changed is only closed
+// once ctx is done
+func (*ArgumentRegions) Watch(ctx context.Context, changed chan<- struct{}) error {
+	context.AfterFunc(ctx, func() { close(changed) })
+	return nil
+}
+
+// RegionsArgumentError is an error in the value of --regions-arg. Pos is
+// the position of the character at which the error occurred starting at 1.
+type RegionsArgumentError struct {
+	Pos int
+	Msg string
+}
+
+func (e *RegionsArgumentError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+type argumentToken struct {
+	text   string
+	pos    int
+	quoted bool
+}
+
+// tokenizeRegionsArgument splits the argument at white space. Tokens that
+// start with a single or double quote extend to the closing quote and can
+// contain white space. Inside of double quotes a backslash escapes the next
+// character.
+func tokenizeRegionsArgument(arg string) (tokens []argumentToken, err error) {
+	runes := []rune(arg)
+
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		t := argumentToken{pos: i + 1}
+		var text strings.Builder
+
+		if quote := runes[i]; quote == '"' || quote == '\'' {
+			t.quoted = true
+			i++
+
+			closed := false
+			for i < len(runes) {
+				if runes[i] == quote {
+					closed = true
+					i++
+					break
+				}
+				if quote == '"' && runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				text.WriteRune(runes[i])
+				i++
+			}
+
+			if !closed {
+				return tokens, &RegionsArgumentError{Pos: t.pos, Msg: "unterminated quote"}
+			}
+			if i < len(runes) && !unicode.IsSpace(runes[i]) {
+				return tokens, &RegionsArgumentError{Pos: i + 1, Msg: "expected white space after the closing quote"}
+			}
+		} else {
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				text.WriteRune(runes[i])
+				i++
+			}
+		}
+
+		t.text = text.String()
+		tokens = append(tokens, t)
+	}
+
+	return
+}
+
+// parseRegionsArgument parses a list of regions in the format
+// 'X,Y WxH NAME ...'. The name is optional and can be put in front of or
+// behind the geometry. A name belongs to the region before it if that region
+// does not have a name yet and to the region after it otherwise. A negative
+// width or height extends the region to the left or top. The regions that
+// have been parsed before an error occurred are returned with the error.
+func parseRegionsArgument(arg string) (rs []Region, err error) {
+	tokens, err := tokenizeRegionsArgument(arg)
+	if err != nil {
+		return
+	}
+
+	var pendingName *argumentToken
+	var lastNamed bool
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+
+		if t.quoted || (!argumentPositionRegex.MatchString(t.text) && !argumentSizeRegex.MatchString(t.text)) {
+			if len(rs) != 0 && !lastNamed && pendingName == nil {
+				rs[len(rs)-1].Name = t.text
+				lastNamed = true
+			} else if pendingName == nil {
+				pendingName = &tokens[i]
+			} else {
+				return rs, &RegionsArgumentError{Pos: t.pos, Msg: fmt.Sprintf("expected a position like 10,20 after the name \"%s\" but got \"%s\"", pendingName.text, t.text)}
+			}
+			continue
+		}
+
+		position := argumentPositionRegex.FindStringSubmatch(t.text)
+		if position == nil {
+			return rs, &RegionsArgumentError{Pos: t.pos, Msg: fmt.Sprintf("expected a position like 10,20 before the size \"%s\"", t.text)}
+		}
+
+		if i+1 == len(tokens) {
+			return rs, &RegionsArgumentError{Pos: len([]rune(arg)) + 1, Msg: fmt.Sprintf("expected a size like 100x50 after the position \"%s\"", t.text)}
+		}
+		i++
+		s := tokens[i]
+
+		size := argumentSizeRegex.FindStringSubmatch(s.text)
+		if s.quoted || size == nil {
+			return rs, &RegionsArgumentError{Pos: s.pos, Msg: fmt.Sprintf("expected a size like 100x50 after the position \"%s\" but got \"%s\"", t.text, s.text)}
+		}
+
+		var geo samure.Rect
+		var values [4]int
+		for j, v := range []string{position[1], position[2], size[1], size[2]} {
+			n, err := strconv.ParseInt(v, 10, 32)
+			if err != nil {
+				pos := t.pos
+				if j >= 2 {
+					pos = s.pos
+				}
+				return rs, &RegionsArgumentError{Pos: pos, Msg: fmt.Sprintf("\"%s\" is out of range", v)}
+			}
+			values[j] = int(n)
+		}
+		geo.X, geo.Y, geo.W, geo.H = values[0], values[1], values[2], values[3]
+
+		if geo.W == 0 || geo.H == 0 {
+			return rs, &RegionsArgumentError{Pos: s.pos, Msg: fmt.Sprintf("the size \"%s\" is empty", s.text)}
+		}
+		if geo.W < 0 {
+			geo.X += geo.W
+			geo.W = -geo.W
+		}
+		if geo.H < 0 {
+			geo.Y += geo.H
+			geo.H = -geo.H
+		}
+
+		r := Region{
+			Geo:      geo,
+			Provider: "arg",
+		}
+		lastNamed = pendingName != nil
+		if pendingName != nil {
+			r.Name = pendingName.text
+			pendingName = nil
+		}
+
+		rs = append(rs, r)
+	}
+
+	if pendingName != nil {
+		return rs, &RegionsArgumentError{Pos: pendingName.pos, Msg: fmt.Sprintf("the name \"%s\" does not belong to any region", pendingName.text)}
+	}
+
+	return
+}
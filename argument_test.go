@@ -0,0 +1,158 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	samure "github.com/Samudevv/samurai-render-go"
+)
+
+func TestParseRegionsArgument(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     string
+		regions []Region
+		errPos  int // 0 if no error is expected
+	}{
+		{
+			name: "without names",
+			arg:  "0,0 100x50 100,50 20x30",
+			regions: []Region{
+				{Geo: samure.Rect{X: 0, Y: 0, W: 100, H: 50}},
+				{Geo: samure.Rect{X: 100, Y: 50, W: 20, H: 30}},
+			},
+		},
+		{
+			name: "names behind",
+			arg:  "0,0 100x50 first 100,50 20x30 second",
+			regions: []Region{
+				{Geo: samure.Rect{X: 0, Y: 0, W: 100, H: 50}, Name: "first"},
+				{Geo: samure.Rect{X: 100, Y: 50, W: 20, H: 30}, Name: "second"},
+			},
+		},
+		{
+			name: "names in front",
+			arg:  "first 0,0 100x50 second 100,50 20x30",
+			regions: []Region{
+				{Geo: samure.Rect{X: 0, Y: 0, W: 100, H: 50}, Name: "first"},
+				{Geo: samure.Rect{X: 100, Y: 50, W: 20, H: 30}, Name: "second"},
+			},
+		},
+		{
+			name: "quoted names",
+			arg:  `  0,0 100x50 "left \"half\""   100,0 100x50 'right half' `,
+			regions: []Region{
+				{Geo: samure.Rect{X: 0, Y: 0, W: 100, H: 50}, Name: `left "half"`},
+				{Geo: samure.Rect{X: 100, Y: 0, W: 100, H: 50}, Name: "right half"},
+			},
+		},
+		{
+			name: "quoted name that looks like a position",
+			arg:  `0,0 100x50 "1,2"`,
+			regions: []Region{
+				{Geo: samure.Rect{X: 0, Y: 0, W: 100, H: 50}, Name: "1,2"},
+			},
+		},
+		{
+			name: "negative position and size",
+			arg:  "-1920,-200 1920x1080 left 0,0 -100x-50",
+			regions: []Region{
+				{Geo: samure.Rect{X: -1920, Y: -200, W: 1920, H: 1080}, Name: "left"},
+				{Geo: samure.Rect{X: -100, Y: -50, W: 100, H: 50}},
+			},
+		},
+		{
+			name:    "missing size",
+			arg:     "0,0 100x50 a 10,10",
+			regions: []Region{{Geo: samure.Rect{W: 100, H: 50}, Name: "a"}},
+			errPos:  19,
+		},
+		{
+			name:   "invalid size",
+			arg:    "0,0 100y50",
+			errPos: 5,
+		},
+		{
+			name:   "size without position",
+			arg:    "100x50",
+			errPos: 1,
+		},
+		{
+			name:   "empty size",
+			arg:    "0,0 0x50",
+			errPos: 5,
+		},
+		{
+			name:    "two names",
+			arg:     "0,0 100x50 a b c 10,10 5x5",
+			regions: []Region{{Geo: samure.Rect{W: 100, H: 50}, Name: "a"}},
+			errPos:  16,
+		},
+		{
+			name:    "name without region",
+			arg:     "0,0 100x50 a b",
+			regions: []Region{{Geo: samure.Rect{W: 100, H: 50}, Name: "a"}},
+			errPos:  14,
+		},
+		{
+			name:   "unterminated quote",
+			arg:    `0,0 100x50 "name`,
+			errPos: 12,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rs, err := parseRegionsArgument(test.arg)
+
+			if test.errPos == 0 && err != nil {
+				t.Fatal(err)
+			}
+			if test.errPos != 0 {
+				var argErr *RegionsArgumentError
+				if !errors.As(err, &argErr) {
+					t.Fatalf("expected an error at position %d but got %v", test.errPos, err)
+				}
+				if argErr.Pos != test.errPos {
+					t.Errorf("expected the error at position %d but got %v", test.errPos, err)
+				}
+			}
+
+			if len(rs) != len(test.regions) {
+				t.Fatalf("expected %v but got %v", test.regions, rs)
+			}
+			for i := range rs {
+				test.regions[i].Provider = "arg"
+				if rs[i] != test.regions[i] {
+					t.Errorf("expected %v but got %v", test.regions[i], rs[i])
+				}
+			}
+		})
+	}
+}
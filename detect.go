@@ -0,0 +1,275 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// compositor describes how a compositor with a region provider is detected
+type compositor struct {
+	name    string // The name of the region type
+	env     string // The environment variable that is set inside of the compositor
+	desktop string // The name of the compositor in $XDG_CURRENT_DESKTOP
+	process string // The name of the process of the compositor
+	probe   func() error
+	regions func() Regions
+}
+
+var compositors = []compositor{
+	{
+		name:    "hyprland",
+		env:     "HYPRLAND_INSTANCE_SIGNATURE",
+		desktop: "Hyprland",
+		process: "Hyprland",
+		probe: func() error {
+			return probeSocket(hyprlandSocketPath(HyprlandRequestSocket))
+		},
+		regions: func() Regions { return &HyprlandRegions{} },
+	},
+	{
+		name:    "sway",
+		env:     "SWAYSOCK",
+		desktop: "sway",
+		process: "sway",
+		probe: func() error {
+			return probeSocket(i3SocketPath(context.Background()))
+		},
+		regions: func() Regions { return &SwayRegions{} },
+	},
+	{
+		name:    "niri",
+		env:     "NIRI_SOCKET",
+		desktop: "niri",
+		process: "niri",
+		probe: func() error {
+			if socketPath := os.Getenv("NIRI_SOCKET"); socketPath != "" {
+				return probeSocket(socketPath, nil)
+			}
+			_, err := exec.LookPath("niri")
+			return err
+		},
+		regions: func() Regions { return &NiriRegions{} },
+	},
+	{
+		name:    "wayfire",
+		env:     "WAYFIRE_SOCKET",
+		desktop: "Wayfire",
+		process: "wayfire",
+		probe: func() error {
+			return probeSocket(os.Getenv("WAYFIRE_SOCKET"), nil)
+		},
+		regions: func() Regions { return &WayfireRegions{} },
+	},
+	{
+		name:    "kwin",
+		desktop: "KDE",
+		process: "kwin_wayland",
+		probe:   probeKWin,
+		regions: func() Regions { return &KWinRegions{} },
+	},
+}
+
+// DetectRegions returns the region provider of the running compositor. The
+// environment variables set by compositors and $XDG_CURRENT_DESKTOP are
+// checked first and the processes are only scanned if none of them is set.
+func DetectRegions() Regions {
+	desktops := strings.Split(os.Getenv("XDG_CURRENT_DESKTOP"), ":")
+
+	var found []compositor
+	var reasons []string
+	for _, c := range compositors {
+		var reason string
+		if c.env != "" && os.Getenv(c.env) != "" {
+			reason = c.env + " is set"
+		} else {
+			for _, d := range desktops {
+				if strings.EqualFold(d, c.desktop) {
+					reason = "XDG_CURRENT_DESKTOP contains " + d
+					break
+				}
+			}
+		}
+		if reason == "" {
+			continue
+		}
+
+		if err := c.probe(); err != nil {
+			debugDetection("Not using %s regions although %s: %v\n", c.name, reason, err)
+			continue
+		}
+
+		found = append(found, c)
+		reasons = append(reasons, reason)
+	}
+
+	switch len(found) {
+	case 0:
+	case 1:
+		debugDetection("Using %s regions because %s\n", found[0].name, reasons[0])
+		return found[0].regions()
+	default:
+		// The environment of nested compositors contains the variables of
+		// the outer one as well, so ask who provides our wayland display
+		process, err := waylandCompositorProcess()
+		if err != nil {
+			debugDetection("Could not find the process of the wayland compositor: %v\n", err)
+		}
+		for i, c := range found {
+			if c.process == process {
+				debugDetection("Using %s regions because %s and the wayland display belongs to %s\n", c.name, reasons[i], process)
+				return c.regions()
+			}
+		}
+
+		debugDetection("Using %s regions because %s\n", found[0].name, reasons[0])
+		return found[0].regions()
+	}
+
+	return detectRegionsFromProcesses()
+}
+
+// detectRegionsFromProcesses looks for the process of a compositor
+func detectRegionsFromProcesses() Regions {
+	var stdout strings.Builder
+	ps := exec.Command("ps", "-e")
+	ps.Stderr = os.Stderr
+	ps.Stdout = &stdout
+
+	if err := ps.Run(); err != nil {
+		debugDetection("Could not detect the compositor: %v\n", err)
+		return nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(stdout.String()))
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		for _, c := range compositors {
+			if strings.HasSuffix(line, c.process) {
+				debugDetection("Using %s regions because the process %s is running\n", c.name, c.process)
+				return c.regions()
+			}
+		}
+	}
+
+	debugDetection("Could not detect the compositor\n")
+	return nil
+}
+
+func debugDetection(format string, a ...interface{}) {
+	if flags.Debug {
+		fmt.Fprintf(os.Stderr, format, a...)
+	}
+}
+
+// probeSocket checks whether something listens on the socket at socketPath
+func probeSocket(socketPath string, err error) error {
+	if err != nil {
+		return err
+	}
+	if socketPath == "" {
+		return errors.New("no socket")
+	}
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeKWin checks whether KWin is on the session bus
+func probeKWin() error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var hasOwner bool
+	if err = conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, KWinService).Store(&hasOwner); err != nil {
+		return err
+	}
+	if !hasOwner {
+		return fmt.Errorf("%s is not on the session bus", KWinService)
+	}
+	return nil
+}
+
+// waylandCompositorProcess returns the name of the process that listens on
+// the socket of the wayland display
+func waylandCompositorProcess() (string, error) {
+	socketPath := os.Getenv("WAYLAND_DISPLAY")
+	if socketPath == "" {
+		socketPath = "wayland-0"
+	}
+	if !filepath.IsAbs(socketPath) {
+		socketPath = filepath.Join(os.Getenv("XDG_RUNTIME_DIR"), socketPath)
+	}
+
+	conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: socketPath, Net: "unix"})
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return "", err
+	}
+
+	var cred *syscall.Ucred
+	var credErr error
+	if err = rawConn.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return "", err
+	}
+	if credErr != nil {
+		return "", credErr
+	}
+
+	comm, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(int(cred.Pid)), "comm"))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(comm)), nil
+}
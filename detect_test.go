@@ -0,0 +1,80 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// clearCompositorEnv removes the variables of all compositors from the
+// environment of the test
+func clearCompositorEnv(t *testing.T) {
+	for _, c := range compositors {
+		if c.env != "" {
+			t.Setenv(c.env, "")
+		}
+	}
+	t.Setenv("XDG_CURRENT_DESKTOP", "")
+	t.Setenv("I3SOCK", "")
+}
+
+func TestDetectRegions(t *testing.T) {
+	t.Run("environment", func(t *testing.T) {
+		clearCompositorEnv(t)
+		startHyprlandServer(t, nil)
+
+		if _, ok := DetectRegions().(*HyprlandRegions); !ok {
+			t.Error("expected hyprland regions")
+		}
+	})
+
+	t.Run("desktop", func(t *testing.T) {
+		clearCompositorEnv(t)
+		startI3IPCServer(t, nil)
+		// sway is only found through XDG_CURRENT_DESKTOP and its socket
+		// is reached through I3SOCK
+		t.Setenv("I3SOCK", os.Getenv("SWAYSOCK"))
+		t.Setenv("SWAYSOCK", "")
+		t.Setenv("XDG_CURRENT_DESKTOP", "sway:wlroots")
+
+		if _, ok := DetectRegions().(*SwayRegions); !ok {
+			t.Error("expected sway regions")
+		}
+	})
+
+	t.Run("unreachable socket", func(t *testing.T) {
+		clearCompositorEnv(t)
+		startI3IPCServer(t, nil)
+		t.Setenv("WAYFIRE_SOCKET", filepath.Join(t.TempDir(), "wayfire.sock"))
+
+		if _, ok := DetectRegions().(*SwayRegions); !ok {
+			t.Error("expected sway regions")
+		}
+	})
+}
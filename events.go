@@ -90,6 +90,8 @@ func (a *App) pointerDown(ctx samure.Context, px, py float64, focus samure.Outpu
 			if sel.PointInOutput(int(px), int(py)) {
 				a.state = StateDragMiddle
 			} else {
+				// A new selection does not belong to the region anymore
+				a.selectedRegion = Region{}
 				a.selectedOutput = focus
 				a.grabberAnim = 0.0
 				a.anchor[0], a.anchor[1] = px, py
@@ -100,8 +102,10 @@ func (a *App) pointerDown(ctx samure.Context, px, py float64, focus samure.Outpu
 			}
 		}
 	case StateChooseRegion:
-		a.cancelled = !isRegionSet(a.selectedRegion.Geo)
-		ctx.SetRunning(false)
+		// The region is picked on release unless the pointer is dragged
+		a.selectedOutput = focus
+		a.anchor[0], a.anchor[1] = px, py
+		a.regionPressed = true
 	case StateChooseOutput:
 		ctx.SetRunning(a.selectedOutput.Handle == nil)
 	}
@@ -134,6 +138,16 @@ func (a *App) pointerUp(ctx samure.Context) {
 		fallthrough
 	case StateDragMiddle:
 		a.state = StateAlter
+	case StateChooseRegion:
+		if a.regionPressed {
+			a.regionPressed = false
+			if flags.AlterSelection && isRegionSet(a.selectedRegion.Geo) {
+				a.alterRegion(ctx)
+			} else {
+				a.cancelled = !isRegionSet(a.selectedRegion.Geo)
+				ctx.SetRunning(false)
+			}
+		}
 	}
 }
 
@@ -227,49 +241,26 @@ func (a *App) pointerMove(ctx samure.Context, px, py, dx, dy float64, focus samu
 		a.selectedOutput = focus
 		ctx.SetRenderState(samure.RenderStateOnce)
 	case StateChooseRegion:
+		if a.regionPressed && math.Hypot(px-a.anchor[0], py-a.anchor[1]) > DragThreshold {
+			a.dragFromRegion(ctx, px, py)
+			break
+		}
+
 		a.selectedOutput = focus
-		prevRegion := a.selectedRegion
-		unsetRegion(&a.selectedRegion.Geo)
 
-		for i := range a.regions {
-			if a.regions[i].Geo.PointInOutput(int(px), int(py)) {
-				a.selectedRegion = a.regions[i]
-				break
-			}
+		// Keep the container that has been walked up to as long as the
+		// pointer stays on the same region
+		hovered := a.regionAt(int(px), int(py))
+		if hovered == a.hoveredRegion {
+			break
 		}
+		a.hoveredRegion = hovered
+		a.regionStack = nil
 
+		prevRegion := a.selectedRegion
+		a.selectedRegion = hovered
 		if a.selectedRegion != prevRegion {
-			if a.regionAnim < 1.0 {
-				a.startRegionAnim = a.currentRegionAnim
-			} else {
-				if isRegionSet(prevRegion.Geo) {
-					a.startRegionAnim[0] = float64(prevRegion.Geo.X)
-					a.startRegionAnim[1] = float64(prevRegion.Geo.Y)
-					a.startRegionAnim[2] = float64(prevRegion.Geo.X + prevRegion.Geo.W)
-					a.startRegionAnim[3] = float64(prevRegion.Geo.Y + prevRegion.Geo.H)
-				} else {
-					a.startRegionAnim[0] = float64(a.selectedRegion.Geo.X + a.selectedRegion.Geo.W/2)
-					a.startRegionAnim[1] = float64(a.selectedRegion.Geo.Y + a.selectedRegion.Geo.H/2)
-					a.startRegionAnim[2] = float64(a.selectedRegion.Geo.X + a.selectedRegion.Geo.W/2)
-					a.startRegionAnim[3] = float64(a.selectedRegion.Geo.Y + a.selectedRegion.Geo.H/2)
-				}
-			}
-
-			if isRegionSet(a.selectedRegion.Geo) {
-				a.endRegionAnim[0] = float64(a.selectedRegion.Geo.X)
-				a.endRegionAnim[1] = float64(a.selectedRegion.Geo.Y)
-				a.endRegionAnim[2] = float64(a.selectedRegion.Geo.X + a.selectedRegion.Geo.W)
-				a.endRegionAnim[3] = float64(a.selectedRegion.Geo.Y + a.selectedRegion.Geo.H)
-			} else {
-				a.endRegionAnim[0] = float64(prevRegion.Geo.X + prevRegion.Geo.W/2)
-				a.endRegionAnim[1] = float64(prevRegion.Geo.Y + prevRegion.Geo.H/2)
-				a.endRegionAnim[2] = float64(prevRegion.Geo.X + prevRegion.Geo.W/2)
-				a.endRegionAnim[3] = float64(prevRegion.Geo.Y + prevRegion.Geo.H/2)
-			}
-
-			a.regionAnim = 0.0
-
-			ctx.SetRenderState(samure.RenderStateOnce)
+			a.animateRegion(ctx, prevRegion)
 		}
 	case StateChooseOutput:
 		prevOutput := a.selectedOutput
@@ -288,6 +279,104 @@ func (a *App) pointerMove(ctx samure.Context, px, py, dx, dy float64, focus samu
 	}
 }
 
+// dragFromRegion leaves the choice of regions to select freely from the
+// position where the pointer has been pressed
+func (a *App) dragFromRegion(ctx samure.Context, px, py float64) {
+	a.regionPressed = false
+	a.stopRegionsWorker()
+
+	a.selectedRegion = Region{}
+	a.hoveredRegion = Region{}
+	a.regionStack = nil
+	a.computeStartEnd(px, py, a.anchor[0], a.anchor[1])
+
+	a.state = StateDragNormal
+	ctx.SetPointerShape(a.getCursorShape())
+	ctx.SetRenderState(samure.RenderStateOnce)
+}
+
+// alterRegion lets the selected region be altered using the grabbers. The
+// values of the region are still reported after it has been altered.
+func (a *App) alterRegion(ctx samure.Context) {
+	a.stopRegionsWorker()
+
+	geo := a.selectedRegion.Geo
+	a.start[0], a.start[1] = float64(geo.X), float64(geo.Y)
+	a.end[0], a.end[1] = float64(geo.X+geo.W), float64(geo.Y+geo.H)
+	// The selection is grown to the aspect ratio like a dragged one
+	a.handleOverlapAndAspectRatio()
+	a.grabberAnim = 0.0
+
+	a.state = StateAlter
+	ctx.SetPointerShape(a.getCursorShape())
+	ctx.SetRenderState(samure.RenderStateOnce)
+}
+
+// animateRegion starts the animation from prevRegion to the selected region
+func (a *App) animateRegion(ctx samure.Context, prevRegion Region) {
+	if a.regionAnim < 1.0 {
+		a.startRegionAnim = a.currentRegionAnim
+	} else {
+		if isRegionSet(prevRegion.Geo) {
+			a.startRegionAnim[0] = float64(prevRegion.Geo.X)
+			a.startRegionAnim[1] = float64(prevRegion.Geo.Y)
+			a.startRegionAnim[2] = float64(prevRegion.Geo.X + prevRegion.Geo.W)
+			a.startRegionAnim[3] = float64(prevRegion.Geo.Y + prevRegion.Geo.H)
+		} else {
+			a.startRegionAnim[0] = float64(a.selectedRegion.Geo.X + a.selectedRegion.Geo.W/2)
+			a.startRegionAnim[1] = float64(a.selectedRegion.Geo.Y + a.selectedRegion.Geo.H/2)
+			a.startRegionAnim[2] = float64(a.selectedRegion.Geo.X + a.selectedRegion.Geo.W/2)
+			a.startRegionAnim[3] = float64(a.selectedRegion.Geo.Y + a.selectedRegion.Geo.H/2)
+		}
+	}
+
+	if isRegionSet(a.selectedRegion.Geo) {
+		a.endRegionAnim[0] = float64(a.selectedRegion.Geo.X)
+		a.endRegionAnim[1] = float64(a.selectedRegion.Geo.Y)
+		a.endRegionAnim[2] = float64(a.selectedRegion.Geo.X + a.selectedRegion.Geo.W)
+		a.endRegionAnim[3] = float64(a.selectedRegion.Geo.Y + a.selectedRegion.Geo.H)
+	} else {
+		a.endRegionAnim[0] = float64(prevRegion.Geo.X + prevRegion.Geo.W/2)
+		a.endRegionAnim[1] = float64(prevRegion.Geo.Y + prevRegion.Geo.H/2)
+		a.endRegionAnim[2] = float64(prevRegion.Geo.X + prevRegion.Geo.W/2)
+		a.endRegionAnim[3] = float64(prevRegion.Geo.Y + prevRegion.Geo.H/2)
+	}
+
+	a.regionAnim = 0.0
+
+	ctx.SetRenderState(samure.RenderStateOnce)
+}
+
+// selectParentRegion selects the container of the selected region
+func (a *App) selectParentRegion(ctx samure.Context) {
+	if !isRegionSet(a.selectedRegion.Geo) || a.selectedRegion.Parent == 0 {
+		return
+	}
+
+	for _, r := range a.regions {
+		if r.Provider == a.selectedRegion.Provider && r.ID == a.selectedRegion.Parent {
+			prevRegion := a.selectedRegion
+			a.regionStack = append(a.regionStack, prevRegion)
+			a.selectedRegion = r
+			a.animateRegion(ctx, prevRegion)
+			return
+		}
+	}
+}
+
+// selectChildRegion walks back down to the region that has been selected
+// before selectParentRegion
+func (a *App) selectChildRegion(ctx samure.Context) {
+	if len(a.regionStack) == 0 {
+		return
+	}
+
+	prevRegion := a.selectedRegion
+	a.selectedRegion = a.regionStack[len(a.regionStack)-1]
+	a.regionStack = a.regionStack[:len(a.regionStack)-1]
+	a.animateRegion(ctx, prevRegion)
+}
+
 func (a *App) OnEvent(ctx samure.Context, event interface{}) {
 	switch e := event.(type) {
 	case samure.EventPointerButton:
@@ -322,13 +411,7 @@ func (a *App) OnEvent(ctx samure.Context, event interface{}) {
 	case samure.EventPointerMotion:
 		px := e.X + float64(e.Seat.PointerFocus().Output().Geo().X)
 		py := e.Y + float64(e.Seat.PointerFocus().Output().Geo().Y)
-		dx := px - a.pointer[0]
-		dy := py - a.pointer[1]
-		a.pointer[0], a.pointer[1] = px, py
-
-		ctx.SetPointerShape(a.getCursorShape())
-
-		a.pointerMove(ctx, px, py, dx, dy, e.Seat.PointerFocus().Output())
+		a.pointerMotion(ctx, px, py, e.Seat.PointerFocus().Output())
 	case samure.EventTouchMotion:
 		if a.touchID == nil || *a.touchID != e.TouchID {
 			break
@@ -341,10 +424,11 @@ func (a *App) OnEvent(ctx samure.Context, event interface{}) {
 		a.pointer[0], a.pointer[1] = px, py
 		a.pointerMove(ctx, px, py, dx, dy, e.Seat.TouchFocus().Output())
 	case samure.EventPointerEnter:
-		switch a.state {
-		case StateNone:
-			ctx.SetPointerShape(samure.CursorShapeCrosshair)
-		}
+		// The position is already known when the pointer enters the surface,
+		// which lets the region below it be highlighted before it is moved
+		px := e.X + float64(e.Output.Geo().X)
+		py := e.Y + float64(e.Output.Geo().Y)
+		a.pointerMotion(ctx, px, py, e.Output)
 	case samure.EventKeyboardKey:
 		if e.Key == samure.KeyEsc && e.State == samure.StateReleased {
 			a.cancelled = true
@@ -357,11 +441,35 @@ func (a *App) OnEvent(ctx samure.Context, event interface{}) {
 			if e.Key == samure.KeyEnter && e.State == samure.StateReleased {
 				ctx.SetRunning(false)
 			}
+		case StateChooseRegion:
+			if e.State == samure.StatePressed {
+				switch e.Key {
+				case KeyUp:
+					a.selectParentRegion(ctx)
+				case KeyDown:
+					a.selectChildRegion(ctx)
+				}
+			}
 		}
 
 	}
 }
 
+// pointerMotion moves the pointer to px,py in global coordinates. The first
+// position of the pointer initialises the selection instead of moving it.
+func (a *App) pointerMotion(ctx samure.Context, px, py float64, focus samure.Output) {
+	if a.pointerKnown {
+		dx := px - a.pointer[0]
+		dy := py - a.pointer[1]
+		a.pointer[0], a.pointer[1] = px, py
+		a.pointerMove(ctx, px, py, dx, dy, focus)
+	} else {
+		a.initPointer(ctx, px, py)
+	}
+
+	ctx.SetPointerShape(a.getCursorShape())
+}
+
 func (a *App) computeStartEnd(px, py, ax, ay float64) {
 	width := math.Abs(px - ax)
 	height := math.Abs(py - ay)
@@ -0,0 +1,331 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	ExecTimeout         = time.Second
+	ExecCursorTimeout   = 100 * time.Millisecond
+	ExecRefreshInterval = 500 * time.Millisecond
+)
+
+// ExecRegions retrieves the regions from a helper program. The helper prints
+// one json object per line to standard output:
+//
+//	{"x": 0, "y": 0, "w": 100, "h": 100, "name": "..."} adds a region
+//	{"type": "done"} ends the list of regions printed since the last one
+//	{"type": "cursor", "x": 10, "y": 10} reports the position of the cursor
+//
+// Helpers that exit are run again when the regions are needed again. Helpers
+// that keep running can print new lists of regions whenever the regions
+// change. The line "cursor" is written to the standard input of running
+// helpers if the position of the cursor is needed.
+type ExecRegions struct {
+	command string
+
+	mutex      sync.Mutex
+	regions    []Region
+	cursor     *[2]int
+	cursorChan chan [2]int
+	stdin      io.WriteCloser
+	helper     *exec.Cmd
+	helperDone chan struct{} // Closed once the output of the helper has been read
+	running    bool
+	closed     bool
+	lastRun    time.Time
+	firstList  chan struct{}
+	changed    chan<- struct{}
+}
+
+type execMessage struct {
+	JSONRegion
+	Type string
+}
+
+// start runs the helper and reads its output in the background. It needs
+// to be called with the mutex locked.
+func (e *ExecRegions) start() error {
+	if e.closed {
+		return errors.New("the region helper has been closed")
+	}
+
+	helper := exec.Command("sh", "-c", e.command)
+	helper.Stderr = os.Stderr
+	// The helper gets its own process group so that Close also stops the
+	// programs that it runs
+	helper.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	stdout, err := helper.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stdin, err := helper.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	if err = helper.Start(); err != nil {
+		return err
+	}
+
+	e.stdin = stdin
+	e.helper = helper
+	e.helperDone = make(chan struct{})
+	e.running = true
+	e.lastRun = time.Now()
+	e.cursor = nil
+	e.cursorChan = make(chan [2]int, 1)
+	if e.firstList == nil {
+		e.firstList = make(chan struct{})
+	}
+
+	go e.read(helper, stdout, e.helperDone)
+
+	return nil
+}
+
+func (e *ExecRegions) read(helper *exec.Cmd, stdout io.Reader, done chan<- struct{}) {
+	defer close(done)
+
+	var pending []Region
+	var committed bool
+
+	commit := func() {
+		e.mutex.Lock()
+		e.regions = pending
+		select {
+		case <-e.firstList:
+		default:
+			close(e.firstList)
+		}
+		if e.changed != nil {
+			notifyChanged(e.changed)
+		}
+		e.mutex.Unlock()
+
+		committed = true
+		pending = nil
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var msg execMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			fmt.Fprintf(os.Stderr, "Region helper printed invalid line \"%s\": %v\n", line, err)
+			continue
+		}
+
+		switch msg.Type {
+		case "", "region":
+			r := msg.Region()
+			r.Provider = "exec"
+			pending = append(pending, r)
+		case "done":
+			commit()
+		case "cursor":
+			cursor := [2]int{int(msg.X), int(msg.Y)}
+			e.mutex.Lock()
+			e.cursor = &cursor
+			e.mutex.Unlock()
+
+			select {
+			case e.cursorChan <- cursor:
+			default:
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "Region helper printed invalid type \"%s\"\n", msg.Type)
+		}
+	}
+
+	// Helpers that print the regions only once do not need to end the list
+	if len(pending) != 0 || !committed {
+		commit()
+	}
+
+	err := helper.Wait()
+
+	e.mutex.Lock()
+	closed := e.closed
+	e.mutex.Unlock()
+
+	// Helpers that have been stopped by Close are expected to fail
+	if err != nil && !closed {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			fmt.Fprintf(os.Stderr, "Region helper \"%s\" exited with status %d\n", e.command, exitErr.ExitCode())
+		} else {
+			fmt.Fprintf(os.Stderr, "Region helper \"%s\" failed: %v\n", e.command, err)
+		}
+	}
+
+	e.mutex.Lock()
+	e.running = false
+	e.stdin.Close()
+	if e.changed != nil {
+		// The helper does not run anymore, so the regions need to be polled
+		close(e.changed)
+		e.changed = nil
+	}
+	e.mutex.Unlock()
+}
+
+// run runs the helper if it is not running and waits until it has printed
+// its first list of regions
+func (e *ExecRegions) run(ctx context.Context) error {
+	e.mutex.Lock()
+	if !e.running && time.Since(e.lastRun) >= ExecRefreshInterval {
+		if err := e.start(); err != nil {
+			e.mutex.Unlock()
+			return fmt.Errorf("failed to run region helper \"%s\": %w", e.command, err)
+		}
+	}
+	firstList := e.firstList
+	e.mutex.Unlock()
+
+	// Only wait for the first list of regions, later runs update them in the background
+	if firstList != nil {
+		select {
+		case <-firstList:
+		case <-time.After(ExecTimeout):
+			return fmt.Errorf("region helper \"%s\" did not print any regions", e.command)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (e *ExecRegions) OutputRegions(ctx context.Context) ([]Region, error) {
+	if err := e.run(ctx); err != nil {
+		return nil, err
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.regions, nil
+}
+
+// CursorPos asks the running helper for the position of the cursor. The
+// helper is started first if the regions have not been retrieved yet.
+func (e *ExecRegions) CursorPos(ctx context.Context) (int, int, error) {
+	if err := e.run(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	e.mutex.Lock()
+	running := e.running
+	stdin := e.stdin
+	cursorChan := e.cursorChan
+	e.mutex.Unlock()
+
+	if running {
+		if _, err := io.WriteString(stdin, "cursor\n"); err == nil {
+			select {
+			case cursor := <-cursorChan:
+				return cursor[0], cursor[1], nil
+			case <-time.After(ExecCursorTimeout):
+			case <-ctx.Done():
+				return 0, 0, ctx.Err()
+			}
+		}
+	}
+
+	// The helper could have printed the position without being asked
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.cursor != nil {
+		return e.cursor[0], e.cursor[1], nil
+	}
+
+	return 0, 0, ErrNotSupported
+}
+
+func (*ExecRegions) Capabilities() RegionsCapabilities {
+	return CapabilityCursor | CapabilityWatch | CapabilityWindowMetadata
+}
+
+// Close stops the helper and waits until it has exited. It is not run
+// again afterwards.
+func (e *ExecRegions) Close() error {
+	e.mutex.Lock()
+	e.closed = true
+	running := e.running
+	helper := e.helper
+	done := e.helperDone
+	if running {
+		e.stdin.Close()
+		syscall.Kill(-helper.Process.Pid, syscall.SIGKILL)
+	}
+	e.mutex.Unlock()
+
+	if running {
+		<-done
+	}
+	return nil
+}
+
+func (e *ExecRegions) Watch(ctx context.Context, changed chan<- struct{}) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if !e.running {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	e.changed = changed
+
+	context.AfterFunc(ctx, func() {
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		if e.changed == changed {
+			close(changed)
+			e.changed = nil
+		}
+	})
+	return nil
+}
@@ -0,0 +1,159 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExecRegions(t *testing.T) {
+	e := ExecRegions{command: `
+		echo '{"x": 0, "y": 0, "w": 100, "h": 100, "name": "first"}'
+		echo '{"type": "done"}'
+		while read request; do
+			if [ "$request" = cursor ]; then
+				echo '{"type": "cursor", "x": 30, "y": 40}'
+			fi
+		done
+	`}
+
+	changed := make(chan struct{}, 1)
+	if err := e.Watch(context.Background(), changed); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := e.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 || rs[0].Name != "first" {
+		t.Fatalf("wrong regions: %v", rs)
+	}
+
+	x, y, err := e.CursorPos(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x != 30 || y != 40 {
+		t.Errorf("wrong cursor position: %d,%d", x, y)
+	}
+
+	// Closing the standard input ends the helper
+	e.mutex.Lock()
+	e.stdin.Close()
+	e.mutex.Unlock()
+
+	for range changed {
+	}
+}
+
+func TestExecRegionsOnce(t *testing.T) {
+	e := ExecRegions{command: `
+		echo '{"x": 0, "y": 0, "w": 100, "h": 100, "name": "first"}'
+		echo '{"x": 100, "y": 0, "w": 100, "h": 100, "name": "second"}'
+		echo '{"type": "cursor", "x": 150, "y": 50}'
+	`}
+
+	rs, err := e.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 || rs[0].Name != "first" || rs[1].Name != "second" {
+		t.Fatalf("wrong regions: %v", rs)
+	}
+
+	x, y, err := e.CursorPos(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x != 150 || y != 50 {
+		t.Errorf("wrong cursor position: %d,%d", x, y)
+	}
+}
+
+func TestExecRegionsCursorFirst(t *testing.T) {
+	e := ExecRegions{command: `
+		echo '{"x": 0, "y": 0, "w": 100, "h": 100, "name": "first"}'
+		echo '{"type": "done"}'
+		while read request; do
+			if [ "$request" = cursor ]; then
+				echo '{"type": "cursor", "x": 30, "y": 40}'
+			fi
+		done
+	`}
+
+	// The cursor is requested before the regions at startup
+	x, y, err := e.CursorPos(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x != 30 || y != 40 {
+		t.Errorf("wrong cursor position: %d,%d", x, y)
+	}
+
+	e.mutex.Lock()
+	e.stdin.Close()
+	e.mutex.Unlock()
+}
+
+func TestExecRegionsClose(t *testing.T) {
+	// The helper neither reads its standard input nor writes anything
+	e := ExecRegions{command: `
+		echo '{"x": 0, "y": 0, "w": 100, "h": 100, "name": "first"}'
+		echo '{"type": "done"}'
+		while true; do
+			sleep 0.1
+		done
+	`}
+
+	if _, err := e.OutputRegions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		e.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the helper has not been stopped")
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.running {
+		t.Error("the helper is still running")
+	}
+	if err := e.start(); err == nil {
+		t.Error("the helper has been run again after it has been closed")
+	}
+}
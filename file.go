@@ -0,0 +1,215 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	samure "github.com/Samudevv/samurai-render-go"
+)
+
+// slurpRegionRegex matches one region in the format of slurp "X,Y WxH label"
+var slurpRegionRegex = regexp.MustCompile(`^(-?\d+),(-?\d+)\s+(\d+)x(\d+)(?:\s+(.*))?$`)
+
+// FileRegions reads the regions from a file or from standard input if path
+// is empty. The regions are read only once.
+type FileRegions struct {
+	path    string
+	once    sync.Once
+	done    chan struct{} // Closed once the regions have been read
+	regions []Region
+	err     error
+}
+
+type JSONRegion struct {
+	X         float64
+	Y         float64
+	W         float64
+	H         float64
+	Name      string
+	Class     string
+	Workspace string
+	Floating  *bool
+	Pid       int
+	Address   string
+}
+
+func (jr JSONRegion) Region() Region {
+	r := Region{
+		Geo: samure.Rect{
+			X: int(jr.X),
+			Y: int(jr.Y),
+			W: int(jr.W),
+			H: int(jr.H),
+		},
+		Name:      jr.Name,
+		Class:     jr.Class,
+		Workspace: jr.Workspace,
+		Pid:       jr.Pid,
+		Address:   jr.Address,
+	}
+	if jr.Floating != nil {
+		r.Floating = *jr.Floating
+		r.FloatingKnown = true
+	}
+	return r
+}
+
+// OutputRegions starts reading the regions the first time it is called and
+// waits until they have been read or ctx is done. Reading continues in the
+// background if ctx is done, so that later calls can return the regions.
+func (f *FileRegions) OutputRegions(ctx context.Context) ([]Region, error) {
+	f.once.Do(func() {
+		f.done = make(chan struct{})
+		go f.read()
+	})
+
+	select {
+	case <-f.done:
+		return f.regions, f.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("regions have not been read yet: %w", ctx.Err())
+	}
+}
+
+// read reads the regions and closes done afterwards
+func (f *FileRegions) read() {
+	defer close(f.done)
+
+	var r io.Reader
+	name := "standard input"
+	if f.path == "" {
+		r = os.Stdin
+	} else {
+		file, err := os.Open(f.path)
+		if err != nil {
+			f.err = fmt.Errorf("failed to read regions: %w", err)
+			return
+		}
+		defer file.Close()
+
+		r = file
+		name = f.path
+	}
+
+	rs, err := parseRegions(r)
+	if err != nil {
+		f.err = fmt.Errorf("invalid regions in %s: %w", name, err)
+	}
+
+	provider := "file"
+	if f.path == "" {
+		provider = "stdin"
+	}
+	for i := range rs {
+		rs[i].Provider = provider
+	}
+	f.regions = rs
+}
+
+func (*FileRegions) CursorPos(context.Context) (int, int, error) {
+	return 0, 0, ErrNotSupported
+}
+
+func (*FileRegions) Capabilities() RegionsCapabilities {
+	return CapabilityWatch | CapabilityWindowMetadata
+}
+
+// The regions are read only once and therefore never change, so changed is
+// only closed once ctx is done
+func (*FileRegions) Watch(ctx context.Context, changed chan<- struct{}) error {
+	context.AfterFunc(ctx, func() { close(changed) })
+	return nil
+}
+
+// parseRegions parses either a json array of regions or one region per line
+// in the format of slurp. The regions that could be parsed are returned
+// even if an error occurred.
+func parseRegions(r io.Reader) ([]Region, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) != 0 && trimmed[0] == '[' {
+		var jsonRegions []JSONRegion
+		if err = json.Unmarshal(trimmed, &jsonRegions); err != nil {
+			return nil, err
+		}
+
+		rs := make([]Region, 0, len(jsonRegions))
+		for _, jr := range jsonRegions {
+			rs = append(rs, jr.Region())
+		}
+		return rs, nil
+	}
+
+	var rs []Region
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		match := slurpRegionRegex.FindStringSubmatch(line)
+		if match == nil {
+			return rs, fmt.Errorf("line %d: invalid region \"%s\"", lineNumber, line)
+		}
+
+		var geo [4]int
+		for i := range geo {
+			v, err := strconv.ParseInt(match[i+1], 10, 64)
+			if err != nil {
+				return rs, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			geo[i] = int(v)
+		}
+
+		rs = append(rs, Region{
+			Geo: samure.Rect{
+				X: geo[0],
+				Y: geo[1],
+				W: geo[2],
+				H: geo[3],
+			},
+			Name: match[5],
+		})
+	}
+
+	return rs, scanner.Err()
+}
@@ -0,0 +1,144 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	samure "github.com/Samudevv/samurai-render-go"
+)
+
+func TestParseRegions(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		regions []Region
+		err     bool
+	}{
+		{
+			name:  "slurp",
+			input: "10,20 300x400 Firefox Browser\n\n-1920,0 1920x1080\n",
+			regions: []Region{
+				{Geo: samure.Rect{X: 10, Y: 20, W: 300, H: 400}, Name: "Firefox Browser"},
+				{Geo: samure.Rect{X: -1920, Y: 0, W: 1920, H: 1080}},
+			},
+		},
+		{
+			name:  "json",
+			input: `[{"x": 10, "y": 20, "w": 300, "h": 400, "name": "Firefox Browser", "class": "firefox", "pid": 42}]`,
+			regions: []Region{
+				{Geo: samure.Rect{X: 10, Y: 20, W: 300, H: 400}, Name: "Firefox Browser", Class: "firefox", Pid: 42},
+			},
+		},
+		{
+			name:  "invalid line",
+			input: "10,20 300x400 first\n10 20 300 400\n",
+			regions: []Region{
+				{Geo: samure.Rect{X: 10, Y: 20, W: 300, H: 400}, Name: "first"},
+			},
+			err: true,
+		},
+		{
+			name:  "invalid json",
+			input: `[{"x": "10"}]`,
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rs, err := parseRegions(strings.NewReader(test.input))
+			if (err != nil) != test.err {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if len(rs) != len(test.regions) {
+				t.Fatalf("expected %v but got %v", test.regions, rs)
+			}
+			for i := range rs {
+				if rs[i] != test.regions[i] {
+					t.Errorf("expected %v but got %v", test.regions[i], rs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFileRegionsTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "regions")
+	if err := syscall.Mkfifo(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing has been written to the pipe yet
+	f := FileRegions{path: path}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := f.OutputRegions(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a timeout but got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("0,0 100x100 first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The regions are still read in the background
+	rs, err := f.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 || rs[0].Name != "first" {
+		t.Errorf("wrong regions: %v", rs)
+	}
+}
+
+func TestFileRegionsWatch(t *testing.T) {
+	var f FileRegions
+	ctx, cancel := context.WithCancel(context.Background())
+	changed := make(chan struct{}, 1)
+	if err := f.Watch(ctx, changed); err != nil {
+		t.Fatal(err)
+	}
+
+	// The regions never change, but changed is closed once ctx is done
+	cancel()
+	select {
+	case _, ok := <-changed:
+		if ok {
+			t.Error("the regions of a file have changed")
+		}
+	case <-time.After(time.Second):
+		t.Error("changed has not been closed")
+	}
+}
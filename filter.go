@@ -0,0 +1,155 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RegionRule matches regions whose field matches a regular expression. The
+// floating field is matched against "floating" or "tiled".
+type RegionRule struct {
+	field string
+	regex *regexp.Regexp
+}
+
+// ParseRegionRule parses a rule in the format FIELD=REGEX
+func ParseRegionRule(rule string) (RegionRule, error) {
+	field, expr, ok := strings.Cut(rule, "=")
+	if !ok {
+		return RegionRule{}, fmt.Errorf("\"%s\" is not in the format FIELD=REGEX", rule)
+	}
+
+	field = strings.ToLower(strings.TrimSpace(field))
+	switch field {
+	case "title", "name":
+		field = "title"
+	case "class", "app_id":
+		field = "class"
+	case "workspace", "floating":
+	default:
+		return RegionRule{}, fmt.Errorf("invalid field \"%s\" in \"%s\"", field, rule)
+	}
+
+	regex, err := regexp.Compile(expr)
+	if err != nil {
+		return RegionRule{}, fmt.Errorf("invalid regular expression in \"%s\": %w", rule, err)
+	}
+
+	return RegionRule{
+		field: field,
+		regex: regex,
+	}, nil
+}
+
+func (rule RegionRule) Match(r Region) bool {
+	switch rule.field {
+	case "title":
+		return rule.regex.MatchString(r.Name)
+	case "class":
+		return rule.regex.MatchString(r.Class)
+	case "workspace":
+		return rule.regex.MatchString(r.Workspace)
+	case "floating":
+		// Regions whose floating state is unknown match no rule
+		if !r.FloatingKnown {
+			return false
+		}
+		if r.Floating {
+			return rule.regex.MatchString("floating")
+		}
+		return rule.regex.MatchString("tiled")
+	default:
+		return false
+	}
+}
+
+// capability returns what a provider needs to support to report the field
+func (rule RegionRule) capability() RegionsCapabilities {
+	switch rule.field {
+	case "class":
+		return CapabilityClass
+	case "workspace":
+		return CapabilityWorkspace
+	case "floating":
+		return CapabilityFloating
+	}
+	return 0
+}
+
+// padRegions grows every region by padding on each side or shrinks it if
+// padding is negative. Regions that would vanish are removed.
+func padRegions(rs []Region, padding int) []Region {
+	padded := make([]Region, 0, len(rs))
+	for _, r := range rs {
+		r.Geo.X -= padding
+		r.Geo.Y -= padding
+		r.Geo.W += 2 * padding
+		r.Geo.H += 2 * padding
+
+		if r.Geo.W > 0 && r.Geo.H > 0 {
+			padded = append(padded, r)
+		}
+	}
+	return padded
+}
+
+// filterRegions returns the regions that match every include rule and
+// none of the exclude rules. Containers are always kept, so that the
+// parents of the remaining regions can still be selected.
+func filterRegions(rs []Region, include, exclude []RegionRule) []Region {
+	if len(include) == 0 && len(exclude) == 0 {
+		return rs
+	}
+
+	filtered := make([]Region, 0, len(rs))
+
+regionLoop:
+	for _, r := range rs {
+		if r.Container {
+			filtered = append(filtered, r)
+			continue
+		}
+
+		for _, rule := range include {
+			if !rule.Match(r) {
+				continue regionLoop
+			}
+		}
+		for _, rule := range exclude {
+			if rule.Match(r) {
+				continue regionLoop
+			}
+		}
+
+		filtered = append(filtered, r)
+	}
+
+	return filtered
+}
@@ -0,0 +1,159 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"testing"
+
+	samure "github.com/Samudevv/samurai-render-go"
+)
+
+func TestFilterRegions(t *testing.T) {
+	rs := []Region{
+		{Name: "Mozilla Firefox", Class: "firefox", Workspace: "1", FloatingKnown: true},
+		{Name: "Picture-in-Picture", Class: "firefox", Workspace: "1", Floating: true, FloatingKnown: true},
+		{Name: "OBS 30.0.0 - Preview", Class: "com.obsproject.Studio", Workspace: "2", FloatingKnown: true},
+		{Name: "Terminal", Class: "kitty", Workspace: "2", FloatingKnown: true},
+		{Name: "Selection"},
+	}
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		regions []string
+	}{
+		{
+			name:    "no rules",
+			regions: []string{"Mozilla Firefox", "Picture-in-Picture", "OBS 30.0.0 - Preview", "Terminal", "Selection"},
+		},
+		{
+			name:    "include class",
+			include: []string{"class=^firefox$"},
+			regions: []string{"Mozilla Firefox", "Picture-in-Picture"},
+		},
+		{
+			name:    "include class and tiled",
+			include: []string{"app_id=firefox", "floating=tiled"},
+			regions: []string{"Mozilla Firefox"},
+		},
+		{
+			name:    "exclude title",
+			exclude: []string{"title=OBS", "title=Picture"},
+			regions: []string{"Mozilla Firefox", "Terminal", "Selection"},
+		},
+		{
+			name:    "include tiled without unknown",
+			include: []string{"floating=tiled"},
+			regions: []string{"Mozilla Firefox", "OBS 30.0.0 - Preview", "Terminal"},
+		},
+		{
+			name:    "exclude tiled without unknown",
+			exclude: []string{"floating=tiled"},
+			regions: []string{"Picture-in-Picture", "Selection"},
+		},
+		{
+			name:    "include workspace and exclude class",
+			include: []string{"workspace=2"},
+			exclude: []string{"class=kitty"},
+			regions: []string{"OBS 30.0.0 - Preview"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var include, exclude []RegionRule
+			for _, rule := range test.include {
+				r, err := ParseRegionRule(rule)
+				if err != nil {
+					t.Fatal(err)
+				}
+				include = append(include, r)
+			}
+			for _, rule := range test.exclude {
+				r, err := ParseRegionRule(rule)
+				if err != nil {
+					t.Fatal(err)
+				}
+				exclude = append(exclude, r)
+			}
+
+			filtered := filterRegions(rs, include, exclude)
+			if len(filtered) != len(test.regions) {
+				t.Fatalf("expected %v but got %v", test.regions, filtered)
+			}
+			for i := range filtered {
+				if filtered[i].Name != test.regions[i] {
+					t.Errorf("expected %s but got %s", test.regions[i], filtered[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterRegionsContainers(t *testing.T) {
+	rs := []Region{
+		{Name: "Terminal", Class: "kitty", ID: 2, Parent: 1},
+		{Name: "Mozilla Firefox", Class: "firefox", ID: 3, Parent: 1},
+		{ID: 1, Container: true},
+	}
+
+	rule, err := ParseRegionRule("class=firefox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filtered := filterRegions(rs, []RegionRule{rule}, nil)
+	if len(filtered) != 2 || filtered[0].Name != "Mozilla Firefox" || !filtered[1].Container {
+		t.Errorf("expected the firefox window and its container but got %v", filtered)
+	}
+}
+
+func TestParseRegionRuleInvalid(t *testing.T) {
+	for _, rule := range []string{"firefox", "pid=42", "title=("} {
+		if _, err := ParseRegionRule(rule); err == nil {
+			t.Errorf("expected \"%s\" to be invalid", rule)
+		}
+	}
+}
+
+func TestPadRegions(t *testing.T) {
+	rs := []Region{
+		{Geo: samure.Rect{X: 10, Y: 20, W: 100, H: 50}, Name: "large"},
+		{Geo: samure.Rect{X: 0, Y: 0, W: 8, H: 8}, Name: "small"},
+	}
+
+	grown := padRegions(rs, 5)
+	if len(grown) != 2 || grown[0].Geo != (samure.Rect{X: 5, Y: 15, W: 110, H: 60}) {
+		t.Errorf("unexpected grown regions %v", grown)
+	}
+
+	shrunk := padRegions(rs, -4)
+	if len(shrunk) != 1 || shrunk[0].Geo != (samure.Rect{X: 14, Y: 24, W: 92, H: 42}) {
+		t.Errorf("unexpected shrunk regions %v", shrunk)
+	}
+}
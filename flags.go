@@ -34,6 +34,7 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+	"time"
 
 	flag "github.com/jessevdk/go-flags"
 	css "github.com/mazznoer/csscolorparser"
@@ -47,27 +48,35 @@ var flags struct {
 	GrabberColor       string `long:"grabber-color" description:"The fill color of the grabbers for altering the selection" default:"#101010FF"`
 	GrabberBorderColor string `long:"grabber-border-color" description:"The border color of the grabbers for altering the selection" default:"#000000FF"`
 
-	BorderWidth      float64 `long:"border-width" description:"The width of the border in pixels" default:"2.0"`
-	Text             bool    `short:"t" long:"text" description:"Display the selection position and dimensions next to the selection box"`
-	Font             string  `long:"font" description:"Set the font family of the text" default:"sans-serif"`
-	ListFonts        bool    `long:"list-fonts" description:"List installed fonts that can be used"`
-	FontSize         float64 `long:"font-size" description:"Set the font size of the text" default:"16"`
-	TextPadding      float64 `long:"text-padding" description:"The distance between the selection box and each text" default:"10"`
-	FreezeScreen     bool    `short:"z" long:"freeze" description:"Freeze the screen while performing the selection"`
-	Screenshot       bool    `short:"s" long:"screenshot" description:"Use grim to perform a screenshot"`
-	ScreenshotOutput string  `short:"o" long:"output" description:"File path where the screenshot will be stored. See at the man page for the specifiers that can be used" default:"screenshot-%y.%M.%d-%h:%m:%s.png"`
-	ScreenshotFlags  string  `long:"screenshot-flags" description:"These flags are passed to grim when performing the screenshot"`
-	Command          string  `short:"c" long:"cmd" description:"Clear the screen and execute a command. This is useful to perform an action while the screen is frozen. Insert %geometry% where you want to put the resulting geometry."`
-	Format           string  `short:"f" long:"format" description:"Set the format in which the geometry is output. See at the man page for the specifiers that can be used" default:"%x,%y %wx%h"`
-	ForceAspectRatio string  `short:"a" long:"aspect-ratio" description:"Force an aspect ratio for the selection box in the format w:h"`
-	AlterSelection   bool    `short:"A" long:"alter-selection" description:"This flag lets you change the selection box after releasing left click by dragging the box at the edges and corners"`
-	GrabberRadius    float64 `long:"grabber-radius" description:"The radius of the grabbers for altering the selection" default:"7"`
-	Debug            bool    `short:"d" long:"debug" description:"Show developer debug stuff"`
-	NoAnimation      bool    `long:"no-anim" description:"Disable the bouncing animation of the grabbers if alter selection is enabled"`
-	Regions          string  `short:"r" long:"regions" description:"Choose from predefined regions (e.g. windows) on the screen." default:"none" choice:"none" choice:"auto" choice:"hyprland" choice:"sway" choice:"arg"`
-	RegionsArgument  string  `short:"R" long:"regions-arg" description:"Declare a list of regions when using regions mode arg. Format 'X1,Y1 W1xH1 X2,Y2 W2xH2 ...'"`
-	Outputs          bool    `short:"p" long:"outputs" description:"Select an output"`
-	Version          bool    `short:"v" long:"version" description:"Display version information"`
+	BorderWidth      float64       `long:"border-width" description:"The width of the border in pixels" default:"2.0"`
+	Text             bool          `short:"t" long:"text" description:"Display the selection position and dimensions next to the selection box"`
+	Font             string        `long:"font" description:"Set the font family of the text" default:"sans-serif"`
+	ListFonts        bool          `long:"list-fonts" description:"List installed fonts that can be used"`
+	FontSize         float64       `long:"font-size" description:"Set the font size of the text" default:"16"`
+	TextPadding      float64       `long:"text-padding" description:"The distance between the selection box and each text" default:"10"`
+	FreezeScreen     bool          `short:"z" long:"freeze" description:"Freeze the screen while performing the selection"`
+	Screenshot       bool          `short:"s" long:"screenshot" description:"Use grim to perform a screenshot"`
+	ScreenshotOutput string        `short:"o" long:"output" description:"File path where the screenshot will be stored. See at the man page for the specifiers that can be used" default:"screenshot-%y.%M.%d-%h:%m:%s.png"`
+	ScreenshotFlags  string        `long:"screenshot-flags" description:"These flags are passed to grim when performing the screenshot"`
+	Command          string        `short:"c" long:"cmd" description:"Clear the screen and execute a command. This is useful to perform an action while the screen is frozen. Insert %geometry% where you want to put the resulting geometry, see the man page for more values."`
+	Format           string        `short:"f" long:"format" description:"Set the format in which the geometry is output. See at the man page for the specifiers that can be used" default:"%x,%y %wx%h"`
+	ForceAspectRatio string        `short:"a" long:"aspect-ratio" description:"Force an aspect ratio for the selection box in the format w:h"`
+	AlterSelection   bool          `short:"A" long:"alter-selection" description:"This flag lets you change the selection box after releasing left click by dragging the box at the edges and corners. This also applies to picked regions"`
+	GrabberRadius    float64       `long:"grabber-radius" description:"The radius of the grabbers for altering the selection" default:"7"`
+	Debug            bool          `short:"d" long:"debug" description:"Show developer debug stuff"`
+	NoAnimation      bool          `long:"no-anim" description:"Disable the bouncing animation of the grabbers if alter selection is enabled"`
+	Regions          string        `short:"r" long:"regions" description:"Choose from predefined regions (e.g. windows) on the screen. One of none, auto, hyprland, sway, niri, wayfire, kwin, arg, stdin, outputs, usable, workspace, file:PATH or exec:COMMAND. Multiple can be combined in a comma separated list" default:"none"`
+	RegionsArgument  string        `short:"R" long:"regions-arg" description:"Declare a list of regions when using regions mode arg. Format 'X1,Y1 W1xH1 NAME1 X2,Y2 W2xH2 NAME2 ...'"`
+	Strict           bool          `long:"strict" description:"Exit with an error if regions-arg can not be parsed instead of only using the regions before the error"`
+	IncludeRegions   []string      `long:"include" description:"Only offer regions that match a rule in the format FIELD=REGEX, where FIELD is title, class, app_id, workspace or floating. Can be used multiple times, in which case a region needs to match every rule"`
+	ExcludeRegions   []string      `long:"exclude" description:"Do not offer regions that match a rule in the format FIELD=REGEX. Can be used multiple times"`
+	Layers           bool          `long:"layers" description:"Also offer layer surfaces like bars, docks and notifications as regions"`
+	RegionGeometry   string        `long:"region-geometry" description:"Whether the regions of windows include their decorations like borders and title bars" default:"content" choice:"content" choice:"decorations"`
+	RegionPadding    int           `long:"region-padding" description:"Grow every region by this many pixels on each side, negative values shrink them"`
+	RegionsRefresh   time.Duration `long:"regions-refresh" description:"How often the regions are retrieved again if the compositor does not report changes" default:"500ms"`
+	RegionPick       string        `long:"region-pick" description:"Which of the regions below the pointer is highlighted" default:"top" choice:"top" choice:"smallest"`
+	Outputs          bool          `short:"p" long:"outputs" description:"Select an output. Combined with --regions the outputs are offered where there is no other region"`
+	Version          bool          `short:"v" long:"version" description:"Display version information"`
 }
 
 func CreateApp(argv []string) (*App, error) {
@@ -163,58 +172,60 @@ func CreateApp(argv []string) (*App, error) {
 		}
 	}
 
-	switch flags.Regions {
-	case "none":
-	case "auto":
-		a.regionsObj = DetectRegions()
-		if a.regionsObj == nil {
-			fmt.Fprintf(os.Stderr, "Could not detect which compositor is running\n")
+	for _, rule := range flags.IncludeRegions {
+		r, err := ParseRegionRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("--include: %w", err)
 		}
-	case "hyprland":
-		a.regionsObj = &HyprlandRegions{}
-	case "sway":
-		a.regionsObj = &SwayRegions{}
-	case "arg":
-		if len(flags.RegionsArgument) == 0 {
-			fmt.Fprintln(os.Stderr, "regions has been set to \"arg\" but regions-arg is empty")
-		} else {
-			a.regionsObj = &ArgumentRegions{}
+		a.includeRules = append(a.includeRules, r)
+	}
+	for _, rule := range flags.ExcludeRegions {
+		r, err := ParseRegionRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("--exclude: %w", err)
 		}
-	default:
-		fmt.Fprintf(os.Stderr, "Invalid regions: \"%s\"\n", flags.Regions)
+		a.excludeRules = append(a.excludeRules, r)
 	}
 
-	if a.regionsObj != nil {
-		if flags.Outputs {
-			return nil, errors.New("Can not choose regions and outputs at the same time")
+	var providers []Regions
+	for _, name := range splitRegions(flags.Regions) {
+		p, err := a.createRegions(name)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			providers = append(providers, p)
 		}
+	}
 
-		a.state = StateChooseRegion
-		a.regions = a.regionsObj.OutputRegions()
-		x, y, err := a.regionsObj.CursorPos()
-		if err == nil {
-			a.pointer[0] = float64(x)
-			a.pointer[1] = float64(y)
-
-			for i := range a.regions {
-				if a.regions[i].Geo.PointInOutput(x, y) {
-					a.selectedRegion = a.regions[i]
-					break
-				}
-			}
+	// With regions the outputs are offered below them, e.g. on the desktop
+	if flags.Outputs && len(providers) != 0 {
+		if p, _ := a.createRegions("outputs"); p != nil {
+			providers = append(providers, p)
+		}
+	}
 
-			if isRegionSet(a.selectedRegion.Geo) {
-				a.currentRegionAnim[0] = float64(a.selectedRegion.Geo.X)
-				a.currentRegionAnim[1] = float64(a.selectedRegion.Geo.Y)
-				a.currentRegionAnim[2] = float64(a.selectedRegion.Geo.X + a.selectedRegion.Geo.W)
-				a.currentRegionAnim[3] = float64(a.selectedRegion.Geo.Y + a.selectedRegion.Geo.H)
-			}
+	if len(providers) == 1 {
+		a.regionsObj = providers[0]
+	} else if len(providers) > 1 {
+		a.regionsObj = &MultiRegions{providers: providers}
+	}
+
+	if a.regionsObj != nil {
+		if flags.RegionsRefresh <= 0 {
+			return nil, errors.New("regions-refresh needs to be positive")
 		}
+
+		a.warnCapabilities()
+
+		// The regions and the cursor are retrieved in the background once
+		// the context has been created
+		a.state = StateChooseRegion
 	}
 
 	a.regionAnim = 1.0
 
-	if flags.Outputs {
+	if flags.Outputs && a.regionsObj == nil {
 		a.state = StateChooseOutput
 		a.regionsObj = DetectRegions()
 	}
@@ -222,6 +233,117 @@ func CreateApp(argv []string) (*App, error) {
 	return a, nil
 }
 
+// warnCapabilities reports flags that have no effect because the regions do
+// not support them
+func (a *App) warnCapabilities() {
+	caps := a.regionsObj.Capabilities()
+
+	warned := make(map[string]bool)
+	for _, rule := range append(append([]RegionRule{}, a.includeRules...), a.excludeRules...) {
+		if c := rule.capability(); c != 0 && !caps.Has(c) && !warned[rule.field] {
+			warned[rule.field] = true
+			fmt.Fprintf(os.Stderr, "The regions do not report the field \"%s\" which is used by --include or --exclude\n", rule.field)
+		}
+	}
+
+	if flags.Layers && !caps.Has(CapabilityLayers) {
+		fmt.Fprintln(os.Stderr, "The regions do not include layer surfaces, --layers has no effect")
+	}
+	if flags.RegionGeometry == "decorations" && !caps.Has(CapabilityDecorations) {
+		fmt.Fprintln(os.Stderr, "The regions do not report their decorations, --region-geometry has no effect")
+	}
+}
+
+// splitRegions splits the comma separated list of region providers. The
+// command of exec can contain commas, so it always extends to the end.
+func splitRegions(regions string) (names []string) {
+	for regions != "" {
+		if strings.HasPrefix(regions, "exec:") {
+			return append(names, regions)
+		}
+
+		name, rest, _ := strings.Cut(regions, ",")
+		names = append(names, strings.TrimSpace(name))
+		regions = strings.TrimSpace(rest)
+	}
+
+	return
+}
+
+// createRegions creates the region provider with the given name. Invalid
+// providers are reported and skipped unless the error is fatal.
+func (a *App) createRegions(name string) (Regions, error) {
+	switch name {
+	case "none":
+	case "auto":
+		regionsObj := DetectRegions()
+		if regionsObj == nil {
+			fmt.Fprintf(os.Stderr, "Could not detect which compositor is running\n")
+		} else {
+			return regionsObj, nil
+		}
+	case "hyprland":
+		return &HyprlandRegions{}, nil
+	case "sway":
+		return &SwayRegions{}, nil
+	case "niri":
+		return &NiriRegions{}, nil
+	case "wayfire":
+		return &WayfireRegions{}, nil
+	case "kwin":
+		return &KWinRegions{}, nil
+	case "arg":
+		if len(flags.RegionsArgument) == 0 {
+			fmt.Fprintln(os.Stderr, "regions has been set to \"arg\" but regions-arg is empty")
+		} else {
+			rs, err := parseRegionsArgument(flags.RegionsArgument)
+			if err != nil {
+				if flags.Strict {
+					return nil, fmt.Errorf("--regions-arg: %w", err)
+				}
+				fmt.Fprintf(os.Stderr, "Invalid regions-arg: %v\n", err)
+			}
+			return &ArgumentRegions{regions: rs}, nil
+		}
+	case "stdin":
+		return &FileRegions{}, nil
+	case "usable":
+		provider := DetectRegions()
+		if provider == nil {
+			fmt.Fprintf(os.Stderr, "Could not detect which compositor is running\n")
+		} else if area, ok := provider.(UsableAreaProvider); ok {
+			return &UsableRegions{provider: provider, area: area}, nil
+		} else {
+			fmt.Fprintln(os.Stderr, "The usable area of the outputs can only be retrieved from hyprland and sway")
+		}
+	case "workspace":
+		provider := DetectRegions()
+		if provider == nil {
+			fmt.Fprintf(os.Stderr, "Could not detect which compositor is running\n")
+		} else if provider.Capabilities().Has(CapabilityWorkspace) {
+			a.workspaceRegions = &WorkspaceRegions{provider: provider}
+			return a.workspaceRegions, nil
+		} else {
+			fmt.Fprintln(os.Stderr, "The compositor does not report the workspaces of its windows")
+		}
+	case "outputs":
+		if a.outputsRegions == nil {
+			a.outputsRegions = &OutputsRegions{}
+			return a.outputsRegions, nil
+		}
+	default:
+		if path, ok := strings.CutPrefix(name, "file:"); ok && path != "" {
+			return &FileRegions{path: path}, nil
+		} else if command, ok := strings.CutPrefix(name, "exec:"); ok && command != "" {
+			return &ExecRegions{command: command}, nil
+		} else {
+			fmt.Fprintf(os.Stderr, "Invalid regions: \"%s\"\n", name)
+		}
+	}
+
+	return nil, nil
+}
+
 func parseColor(colorString string) [4]float64 {
 	c, err := css.Parse(colorString)
 	if err != nil {
@@ -0,0 +1,141 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	HyprlandRequestSocket = ".socket.sock"
+	HyprlandEventSocket   = ".socket2.sock"
+	HyprlandTimeout       = time.Second
+)
+
+// Events of Hyprland after which the windows on the screen could have changed
+var hyprlandRegionEvents = map[string]bool{
+	"openwindow":         true,
+	"closewindow":        true,
+	"movewindow":         true,
+	"movewindowv2":       true,
+	"changefloatingmode": true,
+	"fullscreen":         true,
+	"workspace":          true,
+	"workspacev2":        true,
+	"activewindow":       true,
+	"activewindowv2":     true,
+	"activespecial":      true,
+	"moveworkspace":      true,
+	"moveworkspacev2":    true,
+	"focusedmon":         true,
+	"monitoradded":       true,
+	"monitorremoved":     true,
+	"openlayer":          true,
+	"closelayer":         true,
+}
+
+// hyprlandSocketPath returns the path of one of the sockets of the running
+// Hyprland instance. Newer versions put them into $XDG_RUNTIME_DIR/hypr and
+// older ones into /tmp/hypr.
+func hyprlandSocketPath(socketName string) (string, error) {
+	signature := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE")
+	if signature == "" {
+		return "", errors.New("HYPRLAND_INSTANCE_SIGNATURE is not set")
+	}
+
+	var dirs []string
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		dirs = append(dirs, filepath.Join(runtimeDir, "hypr"))
+	}
+	dirs = append(dirs, "/tmp/hypr")
+
+	for _, d := range dirs {
+		socketPath := filepath.Join(d, signature, socketName)
+		if _, err := os.Stat(socketPath); err == nil {
+			return socketPath, nil
+		}
+	}
+
+	return "", fmt.Errorf("could not find hyprland socket \"%s\"", socketName)
+}
+
+// hyprlandRequest sends a request to the request socket of Hyprland and
+// returns the raw response. Hyprland closes the connection after it has
+// written the response.
+func hyprlandRequest(ctx context.Context, request string) ([]byte, error) {
+	socketPath, err := hyprlandSocketPath(HyprlandRequestSocket)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := dialContext(ctx, socketPath)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	defer bindConn(ctx, conn, HyprlandTimeout)()
+
+	if _, err = conn.Write([]byte(request)); err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(conn)
+}
+
+// hyprlandRequestJSON sends a request with the json flag and decodes the
+// response into v
+func hyprlandRequestJSON(ctx context.Context, request string, v interface{}) error {
+	response, err := hyprlandRequest(ctx, "j/"+request)
+	if err != nil {
+		return fmt.Errorf("hyprland request \"%s\" failed: %w", request, err)
+	}
+
+	if err = json.Unmarshal(response, v); err != nil {
+		return fmt.Errorf("invalid response to \"%s\": %w", request, err)
+	}
+
+	return nil
+}
+
+// hyprlandEvents connects to the event socket of Hyprland which emits one
+// event per line in the format "EVENT>>DATA"
+func hyprlandEvents(ctx context.Context) (net.Conn, error) {
+	socketPath, err := hyprlandSocketPath(HyprlandEventSocket)
+	if err != nil {
+		return nil, err
+	}
+
+	return dialContext(ctx, socketPath)
+}
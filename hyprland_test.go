@@ -0,0 +1,271 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	samure "github.com/Samudevv/samurai-render-go"
+)
+
+// startHyprlandServer creates a stand-in for the request socket of Hyprland
+// that answers requests with the given responses
+func startHyprlandServer(t *testing.T, responses map[string]string) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	t.Setenv("HYPRLAND_INSTANCE_SIGNATURE", "test")
+
+	socketDir := filepath.Join(runtimeDir, "hypr", "test")
+	if err := os.MkdirAll(socketDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("unix", filepath.Join(socketDir, HyprlandRequestSocket))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			buf := make([]byte, 1024)
+			n, _ := conn.Read(buf)
+			if response, ok := responses[string(buf[:n])]; ok {
+				conn.Write([]byte(response))
+			} else {
+				conn.Write([]byte("unknown request"))
+			}
+			conn.Close()
+		}
+	}()
+}
+
+func TestHyprlandRegions(t *testing.T) {
+	startHyprlandServer(t, map[string]string{
+		"j/clients": `[
+			{"at": [10, 20], "size": [300, 200], "workspace": {"id": 1, "name": "1"}, "floating": false, "mapped": true, "title": "tiled"},
+			{"at": [50, 60], "size": [100, 100], "workspace": {"id": 1, "name": "1"}, "floating": true, "mapped": true, "title": "floating"},
+			{"at": [0, 0], "size": [400, 400], "workspace": {"id": 2, "name": "2"}, "floating": false, "mapped": true, "title": "hidden"}
+		]`,
+		"j/monitors": `[{"activeWorkspace": {"id": 1, "name": "1"}}]`,
+		"cursorpos":  "-120, 45",
+	})
+
+	var h HyprlandRegions
+	rs, err := h.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 {
+		t.Fatalf("expected 2 regions but got %d: %v", len(rs), rs)
+	}
+	if rs[0].Name != "floating" || rs[1].Name != "tiled" {
+		t.Errorf("floating clients should come first: %v", rs)
+	}
+	if rs[1].Geo.X != 10 || rs[1].Geo.Y != 20 || rs[1].Geo.W != 300 || rs[1].Geo.H != 200 {
+		t.Errorf("wrong geometry: %v", rs[1].Geo)
+	}
+
+	x, y, err := h.CursorPos(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x != -120 || y != 45 {
+		t.Errorf("wrong cursor position: %d,%d", x, y)
+	}
+}
+
+func TestHyprlandWatch(t *testing.T) {
+	startHyprlandServer(t, nil)
+
+	l, err := net.Listen("unix", filepath.Join(os.Getenv("XDG_RUNTIME_DIR"), "hypr", "test", HyprlandEventSocket))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte("windowtitle>>5678\nopenwindow>>5678,1,kitty,kitty\n"))
+		conn.Close()
+	}()
+
+	var h HyprlandRegions
+	changed := make(chan struct{}, 1)
+	if err := h.Watch(context.Background(), changed); err != nil {
+		t.Fatal(err)
+	}
+
+	var notifications int
+	for range changed {
+		notifications++
+	}
+
+	if notifications != 1 {
+		t.Errorf("expected 1 notification but got %d", notifications)
+	}
+}
+
+func TestHyprlandLayerRegions(t *testing.T) {
+	startHyprlandServer(t, map[string]string{
+		"j/clients": `[
+			{"at": [0, 30], "size": [1920, 1050], "workspace": {"id": 1, "name": "1"}, "floating": false, "mapped": true, "title": "window"}
+		]`,
+		"j/monitors": `[{"activeWorkspace": {"id": 1, "name": "1"}}]`,
+		"j/layers": fmt.Sprintf(`{
+			"DP-1": {"levels": {
+				"0": [{"x": 0, "y": 0, "w": 1920, "h": 1080, "namespace": "wallpaper", "pid": 10}],
+				"1": [{"x": 0, "y": 1000, "w": 1920, "h": 80, "namespace": "dock", "pid": 11}],
+				"2": [
+					{"x": 0, "y": 0, "w": 1920, "h": 30, "namespace": "waybar", "pid": 12},
+					{"x": 0, "y": 0, "w": 1920, "h": 1080, "namespace": "samurai-select", "pid": %d}
+				],
+				"3": [{"x": 1500, "y": 40, "w": 400, "h": 100, "namespace": "notifications", "pid": 13}]
+			}}
+		}`, os.Getpid()),
+	})
+
+	flags.Layers = true
+	defer func() { flags.Layers = false }()
+
+	var h HyprlandRegions
+	rs, err := h.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"notifications", "waybar", "window", "dock"}
+	if len(rs) != len(names) {
+		t.Fatalf("expected %d regions but got %d: %v", len(names), len(rs), rs)
+	}
+	for i := range names {
+		if rs[i].Name != names[i] {
+			t.Errorf("expected region %d to be \"%s\" but got \"%s\"", i, names[i], rs[i].Name)
+		}
+	}
+}
+
+func TestHyprlandRegionsVisibility(t *testing.T) {
+	startHyprlandServer(t, map[string]string{
+		"j/clients": `[
+			{"at": [0, 0], "size": [1920, 1080], "workspace": {"id": 1, "name": "1"}, "floating": false, "mapped": true, "fullscreen": 2, "title": "fullscreen"},
+			{"at": [0, 0], "size": [960, 1080], "workspace": {"id": 1, "name": "1"}, "floating": false, "mapped": true, "fullscreen": 0, "title": "covered"},
+			{"at": [1920, 0], "size": [1920, 1080], "workspace": {"id": 2, "name": "2"}, "floating": false, "mapped": true, "fullscreen": true, "title": "old fullscreen"},
+			{"at": [2000, 100], "size": [300, 300], "workspace": {"id": 2, "name": "2"}, "floating": true, "mapped": true, "fullscreen": false, "title": "floating"},
+			{"at": [1920, 0], "size": [960, 1080], "workspace": {"id": 2, "name": "2"}, "floating": false, "mapped": true, "fullscreen": false, "title": "tiled"},
+			{"at": [2100, 100], "size": [500, 500], "workspace": {"id": -98, "name": "special:magic"}, "floating": false, "mapped": true, "title": "special"},
+			{"at": [2100, 100], "size": [500, 500], "workspace": {"id": 2, "name": "2"}, "floating": false, "mapped": true, "hidden": true, "title": "group member"},
+			{"at": [2100, 100], "size": [500, 500], "workspace": {"id": 2, "name": "2"}, "floating": true, "mapped": false, "title": "unmapped"}
+		]`,
+		"j/monitors": `[
+			{"activeWorkspace": {"id": 1, "name": "1"}, "specialWorkspace": {"id": 0, "name": ""}},
+			{"activeWorkspace": {"id": 2, "name": "2"}, "specialWorkspace": {"id": -98, "name": "special:magic"}}
+		]`,
+	})
+
+	var h HyprlandRegions
+	rs, err := h.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The boolean fullscreen of older versions counts as real fullscreen
+	names := []string{"special", "fullscreen", "old fullscreen"}
+	if len(rs) != len(names) {
+		t.Fatalf("expected %d regions but got %d: %v", len(names), len(rs), rs)
+	}
+	for i := range names {
+		if rs[i].Name != names[i] {
+			t.Errorf("expected region %d to be \"%s\" but got \"%s\"", i, names[i], rs[i].Name)
+		}
+	}
+}
+
+func TestHyprlandRegionsStacking(t *testing.T) {
+	startHyprlandServer(t, map[string]string{
+		"j/clients": `[
+			{"at": [0, 0], "size": [1920, 1080], "workspace": {"id": 1, "name": "1"}, "floating": false, "mapped": true, "focusHistoryID": 0, "title": "tiled"},
+			{"at": [100, 100], "size": [400, 400], "workspace": {"id": 1, "name": "1"}, "floating": true, "mapped": true, "focusHistoryID": 2, "title": "back"},
+			{"at": [200, 200], "size": [400, 400], "workspace": {"id": 1, "name": "1"}, "floating": true, "mapped": true, "focusHistoryID": 1, "title": "front"}
+		]`,
+		"j/monitors": `[{"activeWorkspace": {"id": 1, "name": "1"}}]`,
+	})
+
+	var h HyprlandRegions
+	rs, err := h.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"front", "back", "tiled"}
+	if len(rs) != len(names) {
+		t.Fatalf("expected %d regions but got %d: %v", len(names), len(rs), rs)
+	}
+	for i := range names {
+		if rs[i].Name != names[i] {
+			t.Errorf("expected region %d to be \"%s\" but got \"%s\"", i, names[i], rs[i].Name)
+		}
+	}
+}
+
+func TestHyprlandUsableAreas(t *testing.T) {
+	startHyprlandServer(t, map[string]string{
+		"j/monitors": `[
+			{"name": "DP-1", "x": 0, "y": 0, "width": 3840, "height": 2160, "scale": 2.0, "transform": 0, "reserved": [0, 60, 0, 0], "activeWorkspace": {"id": 1, "name": "1"}},
+			{"name": "HDMI-A-1", "x": 1920, "y": 0, "width": 1920, "height": 1080, "scale": 1.0, "transform": 1, "reserved": [40, 0, 0, 0], "activeWorkspace": {"id": 2, "name": "2"}}
+		]`,
+	})
+
+	var h HyprlandRegions
+	rs, err := h.UsableAreas(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the size of the monitors is scaled and rotated monitors swap it
+	expected := []Region{
+		{Geo: samure.Rect{X: 0, Y: 60, W: 1920, H: 1020}, Name: "DP-1", Workspace: "1", Provider: "hyprland"},
+		{Geo: samure.Rect{X: 1960, Y: 0, W: 1040, H: 1920}, Name: "HDMI-A-1", Workspace: "2", Provider: "hyprland"},
+	}
+	if !slices.Equal(rs, expected) {
+		t.Errorf("expected %v but got %v", expected, rs)
+	}
+}
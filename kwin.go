@@ -0,0 +1,282 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	samure "github.com/Samudevv/samurai-render-go"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	KWinService         = "org.kde.KWin"
+	KWinScriptingPath   = "/Scripting"
+	KWinScriptingIface  = "org.kde.kwin.Scripting"
+	KWinScriptIface     = "org.kde.kwin.Script"
+	KWinCallbackPath    = "/org/samudevv/SamuraiSelect"
+	KWinCallbackIface   = "org.samudevv.SamuraiSelect"
+	KWinTimeout         = time.Second
+	KWinRefreshInterval = 250 * time.Millisecond
+)
+
+// kwinScript is loaded into KWin to retrieve the windows of the current
+// desktop from top to bottom. Panels and notifications are only included
+// if layer surfaces have been requested. It sends them back by calling the
+// Dump method of the object exported by samurai-select. It works with KWin
+// 5 and 6.
+const kwinScript = `
+var includeLayers = %t;
+var ownPid = %d;
+
+function onCurrentDesktop(w) {
+    if (w.onAllDesktops) {
+        return true;
+    }
+    if (w.desktops !== undefined) {
+        return w.desktops.some(function (d) {
+            return d.id === workspace.currentDesktop.id;
+        });
+    }
+    return w.desktop === workspace.currentDesktop;
+}
+
+function onCurrentActivity(w) {
+    return w.activities.length === 0 || w.activities.indexOf(workspace.currentActivity) !== -1;
+}
+
+function rect(r) {
+    return { x: r.x, y: r.y, width: r.width, height: r.height };
+}
+
+var windows = [];
+var stackingOrder = workspace.stackingOrder;
+for (var i = stackingOrder.length - 1; i >= 0; i--) {
+    var w = stackingOrder[i];
+    var isLayer = w.dock || w.notification || w.criticalNotification;
+    if (!(w.normalWindow || w.dialog || (includeLayers && isLayer)) || w.pid === ownPid) {
+        continue;
+    }
+    if (w.minimized || !onCurrentDesktop(w) || !onCurrentActivity(w)) {
+        continue;
+    }
+
+    windows.push({
+        caption: w.caption,
+        resourceClass: String(w.resourceClass),
+        pid: w.pid,
+        internalId: String(w.internalId),
+        frameGeometry: rect(w.frameGeometry),
+        clientGeometry: rect(w.clientGeometry || w.frameGeometry),
+    });
+}
+
+callDBus("%s", "%s", "%s", "Dump", JSON.stringify({
+    windows: windows,
+    desktop: String(workspace.currentDesktop.name !== undefined ? workspace.currentDesktop.name : workspace.currentDesktop),
+    cursor: { x: workspace.cursorPos.x, y: workspace.cursorPos.y },
+}));
+`
+
+type KWinRegions struct {
+	mutex        sync.Mutex // The regions and the cursor are retrieved concurrently
+	lastDump     kwinDump
+	lastDumpTime time.Time
+}
+
+type KWinGeometry struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+type KWinWindow struct {
+	Caption        string
+	ResourceClass  string       `json:"resourceClass"`
+	Pid            int          `json:"pid"`
+	InternalID     string       `json:"internalId"`
+	FrameGeometry  KWinGeometry `json:"frameGeometry"`
+	ClientGeometry KWinGeometry `json:"clientGeometry"`
+}
+
+type kwinDump struct {
+	Windows []KWinWindow
+	Desktop string // The name of the current desktop
+	Cursor  struct {
+		X float64
+		Y float64
+	}
+}
+
+// kwinCallback is exported on the session bus so that the script loaded
+// into KWin can send its results back
+type kwinCallback struct {
+	dumps chan string
+}
+
+func (c kwinCallback) Dump(data string) *dbus.Error {
+	select {
+	case c.dumps <- data:
+	default:
+	}
+	return nil
+}
+
+// dump loads the script into KWin, runs it and waits for its results. The
+// results are reused for KWinRefreshInterval since loading a script is
+// expensive.
+func (k *KWinRegions) dump(ctx context.Context) (kwinDump, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if time.Since(k.lastDumpTime) < KWinRefreshInterval {
+		return k.lastDump, nil
+	}
+
+	conn, err := dbus.ConnectSessionBus(dbus.WithContext(ctx))
+	if err != nil {
+		return kwinDump{}, err
+	}
+	defer conn.Close()
+
+	callback := kwinCallback{dumps: make(chan string, 1)}
+	if err = conn.Export(callback, KWinCallbackPath, KWinCallbackIface); err != nil {
+		return kwinDump{}, err
+	}
+
+	scriptFile, err := os.CreateTemp("", "samurai-select-*.js")
+	if err != nil {
+		return kwinDump{}, err
+	}
+	defer os.Remove(scriptFile.Name())
+
+	_, err = fmt.Fprintf(
+		scriptFile,
+		kwinScript,
+		flags.Layers,
+		os.Getpid(),
+		conn.Names()[0],
+		KWinCallbackPath,
+		KWinCallbackIface,
+	)
+	scriptFile.Close()
+	if err != nil {
+		return kwinDump{}, err
+	}
+
+	pluginName := filepath.Base(scriptFile.Name())
+	scripting := conn.Object(KWinService, KWinScriptingPath)
+
+	var id int32
+	if err = scripting.CallWithContext(ctx, KWinScriptingIface+".loadScript", 0, scriptFile.Name(), pluginName).Store(&id); err != nil {
+		return kwinDump{}, fmt.Errorf("failed to load KWin script: %w", err)
+	}
+	if id < 0 {
+		return kwinDump{}, errors.New("failed to load KWin script")
+	}
+	defer scripting.Call(KWinScriptingIface+".unloadScript", 0, pluginName)
+
+	script := conn.Object(KWinService, dbus.ObjectPath(fmt.Sprintf("%s/Script%d", KWinScriptingPath, id)))
+	if err = script.CallWithContext(ctx, KWinScriptIface+".run", 0).Err; err != nil {
+		// Older versions of KWin export the script at a different path
+		script = conn.Object(KWinService, dbus.ObjectPath(fmt.Sprintf("/%d", id)))
+		if err = script.CallWithContext(ctx, KWinScriptIface+".run", 0).Err; err != nil {
+			return kwinDump{}, fmt.Errorf("failed to run KWin script: %w", err)
+		}
+	}
+
+	select {
+	case data := <-callback.dumps:
+		var d kwinDump
+		if err = json.Unmarshal([]byte(data), &d); err != nil {
+			return kwinDump{}, fmt.Errorf("invalid data from KWin script: %w", err)
+		}
+
+		k.lastDump = d
+		k.lastDumpTime = time.Now()
+		return d, nil
+	case <-time.After(KWinTimeout):
+		return kwinDump{}, errors.New("KWin script did not respond")
+	case <-ctx.Done():
+		return kwinDump{}, ctx.Err()
+	}
+}
+
+func (k *KWinRegions) OutputRegions(ctx context.Context) (rs []Region, err error) {
+	d, err := k.dump(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, w := range d.Windows {
+		geo := w.ClientGeometry
+		if flags.RegionGeometry == "decorations" {
+			geo = w.FrameGeometry
+		}
+
+		rs = append(rs, Region{
+			Geo: samure.Rect{
+				X: int(geo.X),
+				Y: int(geo.Y),
+				W: int(geo.Width),
+				H: int(geo.Height),
+			},
+			Name:      w.Caption,
+			Class:     w.ResourceClass,
+			Workspace: d.Desktop,
+			Provider:  "kwin",
+			Pid:       w.Pid,
+			Address:   w.InternalID,
+		})
+	}
+
+	// The windows are sorted from top to bottom by the script
+	stackRegions(rs)
+
+	return
+}
+
+func (k *KWinRegions) CursorPos(ctx context.Context) (int, int, error) {
+	d, err := k.dump(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return int(d.Cursor.X), int(d.Cursor.Y), nil
+}
+
+func (*KWinRegions) Capabilities() RegionsCapabilities {
+	return CapabilityCursor | CapabilityClass | CapabilityWorkspace | CapabilityPid | CapabilityAddress | CapabilityLayers | CapabilityDecorations
+}
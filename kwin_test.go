@@ -0,0 +1,173 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// startDBusDaemon starts a private session bus and makes it the session
+// bus of the test
+func startDBusDaemon(t *testing.T) {
+	dbusDaemonPath, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+
+	dbusDaemon := exec.Command(dbusDaemonPath, "--session", "--nofork", "--print-address")
+	stdout, err := dbusDaemon.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = dbusDaemon.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		dbusDaemon.Process.Kill()
+		dbusDaemon.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", strings.TrimSpace(address))
+}
+
+var kwinCallDBusRegex = regexp.MustCompile(`callDBus\("([^"]+)", "([^"]+)", "([^"]+)", "Dump"`)
+
+// mockKWinScripting stands in for the scripting interface of KWin. Instead
+// of running the loaded script it sends dump back to the callback of the
+// script.
+type mockKWinScripting struct {
+	conn    *dbus.Conn
+	dump    string
+	scripts []string
+}
+
+func (m *mockKWinScripting) LoadScript(path, pluginName string) (int32, *dbus.Error) {
+	script, err := os.ReadFile(path)
+	if err != nil {
+		return -1, dbus.MakeFailedError(err)
+	}
+
+	id := int32(len(m.scripts))
+	m.scripts = append(m.scripts, string(script))
+
+	err = m.conn.ExportWithMap(
+		mockKWinScript{m, id},
+		map[string]string{"Run": "run"},
+		dbus.ObjectPath(fmt.Sprintf("/Scripting/Script%d", id)),
+		KWinScriptIface,
+	)
+	if err != nil {
+		return -1, dbus.MakeFailedError(err)
+	}
+	return id, nil
+}
+
+func (m *mockKWinScripting) UnloadScript(pluginName string) (bool, *dbus.Error) {
+	return true, nil
+}
+
+type mockKWinScript struct {
+	m  *mockKWinScripting
+	id int32
+}
+
+func (s mockKWinScript) Run() *dbus.Error {
+	match := kwinCallDBusRegex.FindStringSubmatch(s.m.scripts[s.id])
+	if match == nil {
+		return dbus.MakeFailedError(fmt.Errorf("script does not call back"))
+	}
+
+	s.m.conn.Object(match[1], dbus.ObjectPath(match[2])).Go(match[3]+".Dump", dbus.FlagNoReplyExpected, nil, s.m.dump)
+	return nil
+}
+
+func TestKWinRegions(t *testing.T) {
+	startDBusDaemon(t)
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	m := &mockKWinScripting{
+		conn: conn,
+		dump: `{
+			"windows": [
+				{"caption": "Konsole", "resourceClass": "konsole", "pid": 42,
+				 "frameGeometry": {"x": 10, "y": 20, "width": 800, "height": 600},
+				 "clientGeometry": {"x": 12, "y": 50, "width": 796, "height": 568}}
+			],
+			"cursor": {"x": 100, "y": 200}
+		}`,
+	}
+	err = conn.ExportWithMap(m, map[string]string{
+		"LoadScript":   "loadScript",
+		"UnloadScript": "unloadScript",
+	}, KWinScriptingPath, KWinScriptingIface)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := conn.RequestName(KWinService, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("could not own %s: %v", KWinService, err)
+	}
+
+	var k KWinRegions
+	rs, err := k.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 {
+		t.Fatalf("expected 1 region but got %d: %v", len(rs), rs)
+	}
+	if rs[0].Name != "Konsole" || rs[0].Geo.X != 12 || rs[0].Geo.Y != 50 || rs[0].Geo.W != 796 || rs[0].Geo.H != 568 {
+		t.Errorf("wrong region: %v", rs[0])
+	}
+
+	x, y, err := k.CursorPos(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x != 100 || y != 200 {
+		t.Errorf("wrong cursor position: %d,%d", x, y)
+	}
+}
@@ -57,33 +57,22 @@ func run() int {
 	}
 	defer ctx.Destroy()
 
-	if isRegionSet(a.selectedRegion.Geo) {
-		ctx.SetPointerShape(samure.CursorShapePointer)
-		for i := 0; i < ctx.LenOutputs(); i++ {
-			if ctx.Output(i).RectInOutput(
-				a.selectedRegion.Geo.X,
-				a.selectedRegion.Geo.Y,
-				a.selectedRegion.Geo.W,
-				a.selectedRegion.Geo.H,
-			) {
-				a.selectedOutput = ctx.Output(i)
-			}
-		}
+	if a.outputsRegions != nil {
+		// The outputs are only known now that the context has been created
+		a.outputsRegions.SetOutputs(ctx)
+	}
+	if a.workspaceRegions != nil {
+		a.workspaceRegions.SetOutputs(ctx)
+	}
+
+	if a.state == StateChooseRegion {
+		a.startRegionsWorker()
+	}
+	if a.regionsObj != nil {
+		a.fetchCursorPos()
 	}
 
 	if a.state == StateChooseOutput {
-		if a.regionsObj != nil {
-			x, y, err := a.regionsObj.CursorPos()
-			if err == nil {
-				a.pointer[0] = float64(x)
-				a.pointer[1] = float64(y)
-				for i := 0; i < ctx.LenOutputs(); i++ {
-					if ctx.Output(i).PointInOutput(int(a.pointer[0]), int(a.pointer[1])) {
-						a.selectedOutput = ctx.Output(i)
-					}
-				}
-			}
-		}
 		ctx.SetPointerShape(samure.CursorShapePointer)
 	}
 
@@ -111,6 +100,7 @@ func run() int {
 
 	ctx.SetRenderState(samure.RenderStateOnce)
 	ctx.Run()
+	a.stopRegionsWorker()
 
 	outStr, err := a.createOutputString()
 	if err != nil {
@@ -174,8 +164,9 @@ func run() int {
 		commandArgs := strings.FieldsFunc(flags.Command, func(c rune) bool {
 			return c == ' '
 		})
+		replacer := a.commandReplacer(outStr)
 		for i := range commandArgs {
-			commandArgs[i] = strings.ReplaceAll(commandArgs[i], "%geometry%", outStr)
+			commandArgs[i] = replacer.Replace(commandArgs[i])
 		}
 		cmd := exec.Command(commandArgs[0], commandArgs[1:]...)
 		fmt.Println(cmd.Args)
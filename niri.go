@@ -0,0 +1,340 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	samure "github.com/Samudevv/samurai-render-go"
+)
+
+const NiriTimeout = time.Second
+
+type NiriRegions struct {
+}
+
+type NiriLogicalOutput struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+type NiriOutput struct {
+	Name    string
+	Logical *NiriLogicalOutput
+}
+
+type NiriWorkspace struct {
+	ID       int
+	Idx      int
+	Name     *string
+	Output   string
+	IsActive bool `json:"is_active"`
+}
+
+type NiriWindowLayout struct {
+	TileSize               [2]float64  `json:"tile_size"`
+	WindowSize             [2]float64  `json:"window_size"`
+	TilePosInWorkspaceView *[2]float64 `json:"tile_pos_in_workspace_view"`
+	WindowOffsetInTile     [2]float64  `json:"window_offset_in_tile"`
+}
+
+// Geo returns the global geometry of the window. The tile includes the
+// border and the window is placed at an offset inside of it.
+func (l NiriWindowLayout) Geo(o NiriLogicalOutput) samure.Rect {
+	tilePos := *l.TilePosInWorkspaceView
+
+	if flags.RegionGeometry == "decorations" {
+		return samure.Rect{
+			X: o.X + int(tilePos[0]),
+			Y: o.Y + int(tilePos[1]),
+			W: int(l.TileSize[0]),
+			H: int(l.TileSize[1]),
+		}
+	}
+
+	return samure.Rect{
+		X: o.X + int(tilePos[0]+l.WindowOffsetInTile[0]),
+		Y: o.Y + int(tilePos[1]+l.WindowOffsetInTile[1]),
+		W: int(l.WindowSize[0]),
+		H: int(l.WindowSize[1]),
+	}
+}
+
+type NiriWindow struct {
+	ID          int
+	Pid         *int
+	Title       string
+	AppID       string `json:"app_id"`
+	WorkspaceID *int   `json:"workspace_id"`
+	IsFloating  bool   `json:"is_floating"`
+	Layout      NiriWindowLayout
+}
+
+type niriReply struct {
+	Ok  map[string]json.RawMessage
+	Err *string
+}
+
+// niriRequest sends a request to niri through $NIRI_SOCKET and decodes the
+// response into v. If niri's socket is not available "niri msg" is used.
+func niriRequest(ctx context.Context, request string, v interface{}) error {
+	socketPath := os.Getenv("NIRI_SOCKET")
+	if socketPath == "" {
+		return niriMsgRequest(ctx, request, v)
+	}
+
+	conn, err := niriDial(ctx, socketPath, request)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	defer bindConn(ctx, conn, NiriTimeout)()
+
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+
+	return niriDecodeReply(line, request, v)
+}
+
+// niriDial connects to the socket of niri and sends the request. Every
+// request is encoded as json and terminated by a new line.
+func niriDial(ctx context.Context, socketPath, request string) (net.Conn, error) {
+	conn, err := dialContext(ctx, socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := json.Marshal(request)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if _, err = conn.Write(append(msg, '\n')); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func niriDecodeReply(line []byte, request string, v interface{}) error {
+	var reply niriReply
+	if err := json.Unmarshal(line, &reply); err != nil {
+		return fmt.Errorf("invalid reply to \"%s\": %w", request, err)
+	}
+	if reply.Err != nil {
+		return fmt.Errorf("niri: %s", *reply.Err)
+	}
+
+	response, ok := reply.Ok[request]
+	if !ok {
+		return fmt.Errorf("reply to \"%s\" is missing", request)
+	}
+
+	if err := json.Unmarshal(response, v); err != nil {
+		return fmt.Errorf("invalid reply to \"%s\": %w", request, err)
+	}
+
+	return nil
+}
+
+func niriMsgRequest(ctx context.Context, request string, v interface{}) error {
+	niriPath, err := exec.LookPath("niri")
+	if err != nil {
+		return errors.New("NIRI_SOCKET is not set and niri could not be found")
+	}
+
+	var stdout strings.Builder
+	niri := exec.CommandContext(ctx, niriPath, "msg", "--json", strings.ToLower(request))
+	niri.Stdout = &stdout
+	niri.Stderr = os.Stderr
+	if err = niri.Run(); err != nil {
+		return fmt.Errorf("niri msg %s failed: %w", strings.ToLower(request), err)
+	}
+
+	if err = json.Unmarshal([]byte(stdout.String()), v); err != nil {
+		return fmt.Errorf("invalid output of niri msg %s: %w", strings.ToLower(request), err)
+	}
+
+	return nil
+}
+
+func (*NiriRegions) OutputRegions(ctx context.Context) (rs []Region, err error) {
+	var outputs map[string]NiriOutput
+	if err = niriRequest(ctx, "Outputs", &outputs); err != nil {
+		return
+	}
+
+	var workspaces []NiriWorkspace
+	if err = niriRequest(ctx, "Workspaces", &workspaces); err != nil {
+		return
+	}
+
+	var windows []NiriWindow
+	if err = niriRequest(ctx, "Windows", &windows); err != nil {
+		return
+	}
+
+	// Only the active workspace of every output is visible. The index of
+	// unnamed workspaces is counted per output, so it is prefixed with the
+	// name of the output to keep it unique.
+	activeWorkspaces := make(map[int]NiriLogicalOutput)
+	workspaceNames := make(map[int]string)
+	for _, ws := range workspaces {
+		if !ws.IsActive {
+			continue
+		}
+		if o, ok := outputs[ws.Output]; ok && o.Logical != nil {
+			activeWorkspaces[ws.ID] = *o.Logical
+			if ws.Name != nil {
+				workspaceNames[ws.ID] = *ws.Name
+			} else {
+				workspaceNames[ws.ID] = ws.Output + ":" + strconv.Itoa(ws.Idx)
+			}
+		}
+	}
+
+	var floatingWindows []Region
+
+	for _, w := range windows {
+		if w.WorkspaceID == nil || w.Layout.TilePosInWorkspaceView == nil {
+			continue
+		}
+
+		o, ok := activeWorkspaces[*w.WorkspaceID]
+		if !ok {
+			continue
+		}
+
+		r := Region{
+			Geo:           w.Layout.Geo(o),
+			Name:          w.Title,
+			Class:         w.AppID,
+			Workspace:     workspaceNames[*w.WorkspaceID],
+			Floating:      w.IsFloating,
+			FloatingKnown: true,
+			Provider:      "niri",
+			Address:       strconv.Itoa(w.ID),
+		}
+		if w.Pid != nil {
+			r.Pid = *w.Pid
+		}
+
+		// Windows that have been scrolled out of view are still placed on
+		// the workspace and only the part on the output can be seen
+		outputGeo := samure.Rect{X: o.X, Y: o.Y, W: o.Width, H: o.Height}
+		if r.Geo, ok = intersectRects(r.Geo, outputGeo); !ok {
+			continue
+		}
+
+		if w.IsFloating {
+			floatingWindows = append(floatingWindows, r)
+		} else {
+			rs = append(rs, r)
+		}
+	}
+
+	rs = append(floatingWindows, rs...)
+	stackRegions(rs)
+
+	return
+}
+
+// CursorPos is not supported since niri does not report the position of
+// the cursor
+func (*NiriRegions) CursorPos(context.Context) (int, int, error) {
+	return 0, 0, ErrNotSupported
+}
+
+func (*NiriRegions) Capabilities() RegionsCapabilities {
+	return CapabilityWatch | CapabilityWindowMetadata | CapabilityDecorations
+}
+
+func (*NiriRegions) Watch(ctx context.Context, changed chan<- struct{}) error {
+	socketPath := os.Getenv("NIRI_SOCKET")
+	if socketPath == "" {
+		return errors.New("NIRI_SOCKET is not set")
+	}
+
+	conn, err := niriDial(ctx, socketPath, "EventStream")
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+
+	conn.SetDeadline(time.Now().Add(NiriTimeout))
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+
+	var reply struct {
+		Err *string
+	}
+	if err = json.Unmarshal(line, &reply); err != nil {
+		conn.Close()
+		return err
+	}
+	if reply.Err != nil {
+		conn.Close()
+		return fmt.Errorf("niri: %s", *reply.Err)
+	}
+
+	go func() {
+		defer close(changed)
+		defer context.AfterFunc(ctx, func() { conn.Close() })()
+		defer conn.Close()
+
+		// Every event of niri can change the visible windows
+		for {
+			if _, err := reader.ReadBytes('\n'); err != nil {
+				return
+			}
+			notifyChanged(changed)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,158 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+
+	samure "github.com/Samudevv/samurai-render-go"
+)
+
+// startNiriServer creates a stand-in for the socket of niri that answers
+// requests with the given responses
+func startNiriServer(t *testing.T, responses map[string]string) {
+	socketPath := filepath.Join(t.TempDir(), "niri.sock")
+	t.Setenv("NIRI_SOCKET", socketPath)
+
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			line, _ := bufio.NewReader(conn).ReadBytes('\n')
+			var request string
+			json.Unmarshal(line, &request)
+
+			// niri replies with a single line
+			var response bytes.Buffer
+			if err := json.Compact(&response, []byte(responses[request])); err == nil {
+				conn.Write([]byte(`{"Ok":{"` + request + `":` + response.String() + "}}\n"))
+			} else {
+				conn.Write([]byte(`{"Err":"unknown request"}` + "\n"))
+			}
+			conn.Close()
+		}
+	}()
+}
+
+func TestNiriRegions(t *testing.T) {
+	startNiriServer(t, map[string]string{
+		"Outputs": `{
+			"DP-1": {"name": "DP-1", "logical": {"x": 0, "y": 0, "width": 1920, "height": 1080, "scale": 1.0}},
+			"DP-2": {"name": "DP-2", "logical": {"x": 1920, "y": 0, "width": 1920, "height": 1080, "scale": 1.0}}
+		}`,
+		"Workspaces": `[
+			{"id": 1, "idx": 1, "output": "DP-1", "is_active": true},
+			{"id": 2, "idx": 2, "output": "DP-1", "is_active": false},
+			{"id": 3, "idx": 1, "output": "DP-2", "is_active": true}
+		]`,
+		"Windows": `[
+			{"id": 1, "title": "tiled", "workspace_id": 1, "is_floating": false, "layout": {
+				"tile_size": [964.0, 1044.0], "window_size": [960, 1040],
+				"tile_pos_in_workspace_view": [16.0, 16.0], "window_offset_in_tile": [2.0, 2.0]}},
+			{"id": 2, "title": "scrolled out", "workspace_id": 1, "is_floating": false, "layout": {
+				"tile_size": [964.0, 1044.0], "window_size": [960, 1040],
+				"tile_pos_in_workspace_view": [2000.0, 16.0], "window_offset_in_tile": [2.0, 2.0]}},
+			{"id": 3, "title": "inactive workspace", "workspace_id": 2, "is_floating": false, "layout": {
+				"tile_size": [964.0, 1044.0], "window_size": [960, 1040],
+				"tile_pos_in_workspace_view": [16.0, 16.0], "window_offset_in_tile": [2.0, 2.0]}},
+			{"id": 4, "title": "floating", "workspace_id": 3, "is_floating": true, "layout": {
+				"tile_size": [400.0, 300.0], "window_size": [400, 300],
+				"tile_pos_in_workspace_view": [100.0, 50.0], "window_offset_in_tile": [0.0, 0.0]}}
+		]`,
+	})
+
+	var n NiriRegions
+	rs, err := n.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 {
+		t.Fatalf("expected 2 regions but got %d: %v", len(rs), rs)
+	}
+
+	if rs[0].Name != "floating" || rs[0].Geo.X != 2020 || rs[0].Geo.Y != 50 || rs[0].Geo.W != 400 || rs[0].Geo.H != 300 {
+		t.Errorf("wrong floating window: %v", rs[0])
+	}
+	if rs[1].Name != "tiled" || rs[1].Geo.X != 18 || rs[1].Geo.Y != 18 || rs[1].Geo.W != 960 || rs[1].Geo.H != 1040 {
+		t.Errorf("wrong tiled window: %v", rs[1])
+	}
+}
+
+func TestNiriRegionsPartlyScrolled(t *testing.T) {
+	startNiriServer(t, map[string]string{
+		"Outputs": `{
+			"DP-1": {"name": "DP-1", "logical": {"x": 0, "y": 0, "width": 1920, "height": 1080, "scale": 1.0}},
+			"DP-2": {"name": "DP-2", "logical": {"x": 1920, "y": 0, "width": 1920, "height": 1080, "scale": 1.0}}
+		}`,
+		"Workspaces": `[
+			{"id": 1, "idx": 1, "output": "DP-1", "is_active": true},
+			{"id": 2, "idx": 1, "output": "DP-2", "is_active": true}
+		]`,
+		"Windows": `[
+			{"id": 1, "title": "partly scrolled", "workspace_id": 1, "is_floating": false, "layout": {
+				"tile_size": [960.0, 1080.0], "window_size": [960, 1080],
+				"tile_pos_in_workspace_view": [1800.0, 0.0], "window_offset_in_tile": [0.0, 0.0]}},
+			{"id": 2, "title": "other output", "workspace_id": 2, "is_floating": false, "layout": {
+				"tile_size": [960.0, 1080.0], "window_size": [960, 1080],
+				"tile_pos_in_workspace_view": [0.0, 0.0], "window_offset_in_tile": [0.0, 0.0]}}
+		]`,
+	})
+
+	var n NiriRegions
+	rs, err := n.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 {
+		t.Fatalf("expected 2 regions but got %d: %v", len(rs), rs)
+	}
+
+	// Only the part of the window on its own output can be picked
+	if geo := rs[0].Geo; geo != (samure.Rect{X: 1800, Y: 0, W: 120, H: 1080}) {
+		t.Errorf("the partly scrolled window has not been clipped: %v", geo)
+	}
+
+	// Unnamed workspaces on different outputs have different names
+	if rs[0].Workspace != "DP-1:1" || rs[1].Workspace != "DP-2:1" {
+		t.Errorf("wrong workspaces \"%s\" and \"%s\"", rs[0].Workspace, rs[1].Workspace)
+	}
+}
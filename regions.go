@@ -28,50 +28,152 @@ package main
 
 import (
 	"bufio"
+	"cmp"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net"
 	"os"
-	"os/exec"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	samure "github.com/Samudevv/samurai-render-go"
 )
 
 type Region struct {
-	Geo  samure.Rect
-	Name string
+	Geo           samure.Rect
+	Name          string
+	Class         string // The class or app_id of the window
+	Workspace     string
+	Floating      bool
+	FloatingKnown bool // Whether the provider reports if the region is floating or tiled
+	Provider      string
+	Pid           int    // The process id of the window, 0 if it is unknown
+	Address       string // How the compositor identifies the window, e.g. the address in Hyprland or the con_id in sway
+	ID            int    // Identifies the region among the regions of its provider
+	Parent        int    // The ID of the container of the region, 0 if it has none
+	Container     bool   // Whether the region is a container of other regions
+	Z             int    // The stacking order, regions with a higher Z are on top
 }
 
-type Regions interface {
-	OutputRegions() []Region
-	CursorPos() (int, int, error)
+// intersectRects returns the part of a that is inside of b and whether they
+// overlap at all
+func intersectRects(a, b samure.Rect) (samure.Rect, bool) {
+	x := max(a.X, b.X)
+	y := max(a.Y, b.Y)
+	w := min(a.X+a.W, b.X+b.W) - x
+	h := min(a.Y+a.H, b.Y+b.H) - y
+	if w <= 0 || h <= 0 {
+		return samure.Rect{}, false
+	}
+	return samure.Rect{X: x, Y: y, W: w, H: h}, true
 }
 
-func DetectRegions() Regions {
-	var stdout strings.Builder
-	ps := exec.Command("ps", "-e")
-	ps.Stderr = os.Stderr
-	ps.Stdout = &stdout
+// sortRegions sorts the regions from top to bottom
+func sortRegions(rs []Region) {
+	slices.SortStableFunc(rs, func(a, b Region) int {
+		return cmp.Compare(b.Z, a.Z)
+	})
+}
 
-	if err := ps.Run(); err != nil {
-		return nil
+// stackRegions sets the stacking order of regions that are already sorted
+// from top to bottom
+func stackRegions(rs []Region) {
+	for i := range rs {
+		rs[i].Z = len(rs) - i
 	}
+}
+
+// Regions provides the regions that can be chosen from. Both methods can
+// take a while and should give up once ctx is done.
+type Regions interface {
+	OutputRegions(ctx context.Context) ([]Region, error)
+	CursorPos(ctx context.Context) (int, int, error)
+	Capabilities() RegionsCapabilities
+}
 
-	scanner := bufio.NewScanner(strings.NewReader(stdout.String()))
+// RegionsCapabilities describe what a provider of regions supports
+type RegionsCapabilities uint
+
+const (
+	CapabilityCursor      RegionsCapabilities = 1 << iota // CursorPos is supported
+	CapabilityWatch                                       // The regions can be watched for changes
+	CapabilityClass                                       // The regions report their class
+	CapabilityWorkspace                                   // The regions report their workspace
+	CapabilityFloating                                    // The regions report whether they are floating
+	CapabilityPid                                         // The regions report their pid
+	CapabilityAddress                                     // The regions report their address
+	CapabilityLayers                                      // Layer surfaces are reported with --layers
+	CapabilityDecorations                                 // --region-geometry is supported
+	CapabilityHierarchy                                   // Containers of regions are reported
+
+	// The metadata that is reported by window managers
+	CapabilityWindowMetadata = CapabilityClass | CapabilityWorkspace | CapabilityFloating | CapabilityPid | CapabilityAddress
+)
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasSuffix(line, "Hyprland") {
-			return &HyprlandRegions{}
-		} else if strings.HasSuffix(line, "sway") {
-			return &SwayRegions{}
-		}
-	}
+func (c RegionsCapabilities) Has(capability RegionsCapabilities) bool {
+	return c&capability == capability
+}
+
+// ErrNotSupported is returned by providers for features that they do not
+// support, like the position of the cursor
+var ErrNotSupported = errors.New("not supported")
+
+// bindConn sets the deadline of conn to timeout and interrupts it once ctx
+// is done. The returned function needs to be called once conn is not used
+// anymore.
+func bindConn(ctx context.Context, conn net.Conn, timeout time.Duration) (stop func() bool) {
+	conn.SetDeadline(time.Now().Add(timeout))
+	return context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+}
 
+// dialContext connects to the unix socket at socketPath
+func dialContext(ctx context.Context, socketPath string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", socketPath)
+}
+
+// RegionsWatcher is implemented by Regions that are able to tell when their
+// regions have changed, so that they don't need to be polled. Watch
+// returns an error if the regions can not be watched. Otherwise it sends
+// to changed whenever the regions have changed and closes it as soon as
+// the regions can not be watched anymore or ctx is done.
+type RegionsWatcher interface {
+	Watch(ctx context.Context, changed chan<- struct{}) error
+}
+
+// closeRegions stops everything that provider runs in the background if it
+// implements io.Closer
+func closeRegions(provider Regions) error {
+	if closer, ok := provider.(io.Closer); ok {
+		return closer.Close()
+	}
 	return nil
 }
 
+// UsableAreaProvider is implemented by providers that know which area of
+// the outputs is not covered by bars and other exclusive zones
+type UsableAreaProvider interface {
+	// UsableAreas returns one region per output that is named after it
+	UsableAreas(ctx context.Context) ([]Region, error)
+}
+
+// notifyChanged sends to changed without blocking, since a notification
+// that is still pending already covers this change
+func notifyChanged(changed chan<- struct{}) {
+	select {
+	case changed <- struct{}{}:
+	default:
+	}
+}
+
 type HyprlandRegions struct {
 }
 
@@ -81,121 +183,287 @@ type HyprWorkspace struct {
 }
 
 type HyprMonitor struct {
-	ActiveWorkspace HyprWorkspace
+	Name             string
+	X                int
+	Y                int
+	Width            int // In physical pixels
+	Height           int // In physical pixels
+	Scale            float64
+	Transform        int
+	Reserved         [4]int // The reserved space on the left, top, right and bottom in logical pixels
+	ActiveWorkspace  HyprWorkspace
+	SpecialWorkspace HyprWorkspace // The ID is 0 if no special workspace is shown
+}
+
+// UsableGeo returns the global geometry of the monitor without the space
+// that is reserved by layer surfaces like bars. Only the size of the
+// monitor is in physical pixels and needs to be scaled.
+func (m HyprMonitor) UsableGeo() samure.Rect {
+	scale := m.Scale
+	if scale <= 0.0 {
+		scale = 1.0
+	}
+
+	w := float64(m.Width) / scale
+	h := float64(m.Height) / scale
+	// Monitors that are rotated by 90 or 270 degrees swap their size
+	if m.Transform%2 == 1 {
+		w, h = h, w
+	}
+
+	left, top, right, bottom := m.Reserved[0], m.Reserved[1], m.Reserved[2], m.Reserved[3]
+
+	return samure.Rect{
+		X: m.X + left,
+		Y: m.Y + top,
+		W: int(w) - left - right,
+		H: int(h) - top - bottom,
+	}
+}
+
+const (
+	HyprFullscreenNone      = 0
+	HyprFullscreenMaximized = 1
+	HyprFullscreenFull      = 2
+)
+
+// HyprFullscreen is the fullscreen mode of a client. Older versions of
+// Hyprland report it as a boolean and newer ones as a number.
+type HyprFullscreen int
+
+func (f *HyprFullscreen) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		if b {
+			*f = HyprFullscreenFull
+		} else {
+			*f = HyprFullscreenNone
+		}
+		return nil
+	}
+
+	var i int
+	if err := json.Unmarshal(data, &i); err != nil {
+		return err
+	}
+	*f = HyprFullscreen(i)
+	return nil
 }
 
 type HyprClient struct {
-	At        [2]int
-	Size      [2]int
-	Workspace HyprWorkspace
-	Floating  bool
-	Title     string
+	At             [2]int
+	Size           [2]int
+	Workspace      HyprWorkspace
+	Floating       bool
+	Class          string
+	Title          string
+	Address        string
+	Pid            int
+	FocusHistoryID int `json:"focusHistoryID"` // 0 for the most recently focused client
+	Mapped         bool
+	Hidden         bool // Clients of a group that are not the active one are hidden
+	Fullscreen     HyprFullscreen
 }
 
-func (h HyprClient) IsOnScreen(monitors []HyprMonitor) bool {
+// IsOnScreen returns whether the client is on one of the workspaces that are
+// shown by the monitors and whether that workspace is a special workspace
+func (h HyprClient) IsOnScreen(monitors []HyprMonitor) (onScreen, special bool) {
+	if !h.Mapped || h.Hidden {
+		return
+	}
+
 	if !(h.At[0] != 0 || h.At[1] != 0 || h.Size[0] != 0 || h.Size[1] != 0) {
-		return false
+		return
 	}
 
 	for _, m := range monitors {
+		if m.SpecialWorkspace.ID != 0 && m.SpecialWorkspace.ID == h.Workspace.ID {
+			return true, true
+		}
 		if m.ActiveWorkspace.ID == h.Workspace.ID {
-			return true
+			return true, false
 		}
 	}
 
-	return false
+	return
 }
 
-func (*HyprlandRegions) OutputRegions() (rs []Region) {
-	hyprctlPath, err := exec.LookPath("hyprctl")
-	if err != nil {
-		return
+// IsCovered returns whether the client is hidden behind a fullscreen client
+// of its workspace. Maximized clients only cover the tiled clients.
+func (h HyprClient) IsCovered(fullscreen map[int]HyprFullscreen) bool {
+	if h.Fullscreen != HyprFullscreenNone {
+		return false
 	}
 
-	var stdout strings.Builder
-
-	hyprctl := exec.Command(hyprctlPath, "-j", "clients")
-	hyprctl.Stderr = os.Stderr
-	hyprctl.Stdout = &stdout
-	if err = hyprctl.Run(); err != nil {
-		return
+	switch fullscreen[h.Workspace.ID] {
+	case HyprFullscreenNone:
+		return false
+	case HyprFullscreenMaximized:
+		return !h.Floating
+	default:
+		return true
 	}
+}
 
+func (*HyprlandRegions) OutputRegions(ctx context.Context) (rs []Region, err error) {
 	var clients []HyprClient
-	decoder := json.NewDecoder(strings.NewReader(stdout.String()))
-	if err = decoder.Decode(&clients); err != nil {
+	if err = hyprlandRequestJSON(ctx, "clients", &clients); err != nil {
 		return
 	}
 
-	stdout.Reset()
-	hyprctl = exec.Command(hyprctlPath, "-j", "monitors")
-	hyprctl.Stderr = os.Stderr
-	hyprctl.Stdout = &stdout
-	if err = hyprctl.Run(); err != nil {
+	var monitors []HyprMonitor
+	if err = hyprlandRequestJSON(ctx, "monitors", &monitors); err != nil {
 		return
 	}
 
-	var monitors []HyprMonitor
-	decoder = json.NewDecoder(strings.NewReader(stdout.String()))
-	if err = decoder.Decode(&monitors); err != nil {
-		return
+	// The position and size of clients do not include the border
+	var borderSize HyprOption
+	if flags.RegionGeometry == "decorations" {
+		if err = hyprlandRequestJSON(ctx, "getoption general:border_size", &borderSize); err != nil {
+			return
+		}
 	}
 
-	var floatingClients []Region
+	fullscreen := make(map[int]HyprFullscreen)
+	for _, c := range clients {
+		if c.Mapped && !c.Hidden && c.Fullscreen > fullscreen[c.Workspace.ID] {
+			fullscreen[c.Workspace.ID] = c.Fullscreen
+		}
+	}
 
+	// Floating clients are above the tiled ones and special workspaces are
+	// shown on top of the regular workspaces. Within those the most recently
+	// focused client is on top.
+	n := len(clients) + 2
 	for _, c := range clients {
-		if c.IsOnScreen(monitors) {
-			r := Region{
+		onScreen, special := c.IsOnScreen(monitors)
+		if onScreen && !c.IsCovered(fullscreen) {
+			var level int
+			if c.Floating {
+				level++
+			}
+			if special {
+				level += 2
+			}
+
+			rs = append(rs, Region{
 				Geo: samure.Rect{
-					X: c.At[0],
-					Y: c.At[1],
-					W: c.Size[0],
-					H: c.Size[1],
+					X: c.At[0] - borderSize.Int,
+					Y: c.At[1] - borderSize.Int,
+					W: c.Size[0] + 2*borderSize.Int,
+					H: c.Size[1] + 2*borderSize.Int,
 				},
-				Name: c.Title,
-			}
+				Name:          c.Title,
+				Class:         c.Class,
+				Workspace:     c.Workspace.Name,
+				Floating:      c.Floating,
+				FloatingKnown: true,
+				Provider:      "hyprland",
+				Pid:           c.Pid,
+				Address:       c.Address,
+				Z:             level*n - c.FocusHistoryID,
+			})
+		}
+	}
 
-			if c.Floating {
-				floatingClients = append(floatingClients, r)
-			} else {
-				rs = append(rs, r)
-			}
+	if flags.Layers {
+		var above, below []Region
+		above, below, err = hyprlandLayerRegions(ctx)
+		if err != nil {
+			return
 		}
+
+		for i := range above {
+			above[i].Z = 4*n + len(above) - i
+		}
+		for i := range below {
+			below[i].Z = -n - i
+		}
+		rs = append(append(above, rs...), below...)
 	}
 
-	rs = append(floatingClients, rs...)
+	sortRegions(rs)
 
 	return
 }
 
-func (*HyprlandRegions) CursorPos() (int, int, error) {
-	hyprctlPath, err := exec.LookPath("hyprctl")
-	if err != nil {
-		return 0, 0, err
+type HyprOption struct {
+	Int int
+}
+
+type HyprLayer struct {
+	X         int
+	Y         int
+	W         int
+	H         int
+	Namespace string
+	Pid       int
+	Address   string
+}
+
+type HyprLayerLevels struct {
+	Levels map[string][]HyprLayer
+}
+
+// hyprlandLayerRegions returns the layer surfaces that are above the windows
+// (top and overlay layer) and below them (bottom layer). The background
+// layer and the layer surfaces of samurai-select itself are omitted.
+func hyprlandLayerRegions(ctx context.Context) (above, below []Region, err error) {
+	var monitors map[string]HyprLayerLevels
+	if err = hyprlandRequestJSON(ctx, "layers", &monitors); err != nil {
+		return
 	}
 
-	var stdout strings.Builder
+	layerRegions := func(levels map[string][]HyprLayer, level string) (rs []Region) {
+		for _, l := range levels[level] {
+			if l.Pid == os.Getpid() {
+				continue
+			}
 
-	hyprctl := exec.Command(hyprctlPath, "cursorpos")
-	hyprctl.Stderr = os.Stderr
-	hyprctl.Stdout = &stdout
-	if err = hyprctl.Run(); err != nil {
+			rs = append(rs, Region{
+				Geo: samure.Rect{
+					X: l.X,
+					Y: l.Y,
+					W: l.W,
+					H: l.H,
+				},
+				Name:     l.Namespace,
+				Provider: "hyprland",
+				Pid:      l.Pid,
+				Address:  l.Address,
+			})
+		}
+		return
+	}
+
+	for _, m := range monitors {
+		above = append(above, layerRegions(m.Levels, "3")...)
+		above = append(above, layerRegions(m.Levels, "2")...)
+		below = append(below, layerRegions(m.Levels, "1")...)
+	}
+
+	return
+}
+
+func (*HyprlandRegions) CursorPos(ctx context.Context) (int, int, error) {
+	response, err := hyprlandRequest(ctx, "cursorpos")
+	if err != nil {
 		return 0, 0, err
 	}
 
-	words := strings.Split(stdout.String(), ",")
+	words := strings.Split(string(response), ",")
 	if len(words) != 2 {
-		return 0, 0, errors.New("Invalid output")
+		return 0, 0, fmt.Errorf("invalid response to \"cursorpos\": \"%s\"", response)
 	}
 
 	xStr := strings.TrimSpace(words[0])
 	yStr := strings.TrimSpace(words[1])
 
-	x, err := strconv.ParseUint(xStr, 10, 64)
+	x, err := strconv.ParseInt(xStr, 10, 64)
 	if err != nil {
 		return 0, 0, err
 	}
-	y, err := strconv.ParseUint(yStr, 10, 64)
+	y, err := strconv.ParseInt(yStr, 10, 64)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -203,6 +471,51 @@ func (*HyprlandRegions) CursorPos() (int, int, error) {
 	return int(x), int(y), nil
 }
 
+func (*HyprlandRegions) Capabilities() RegionsCapabilities {
+	return CapabilityCursor | CapabilityWatch | CapabilityWindowMetadata | CapabilityLayers | CapabilityDecorations
+}
+
+func (*HyprlandRegions) UsableAreas(ctx context.Context) (rs []Region, err error) {
+	var monitors []HyprMonitor
+	if err = hyprlandRequestJSON(ctx, "monitors", &monitors); err != nil {
+		return
+	}
+
+	for _, m := range monitors {
+		rs = append(rs, Region{
+			Geo:       m.UsableGeo(),
+			Name:      m.Name,
+			Workspace: m.ActiveWorkspace.Name,
+			Provider:  "hyprland",
+		})
+	}
+
+	return
+}
+
+func (*HyprlandRegions) Watch(ctx context.Context, changed chan<- struct{}) error {
+	conn, err := hyprlandEvents(ctx)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer close(changed)
+		defer context.AfterFunc(ctx, func() { conn.Close() })()
+		defer conn.Close()
+
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			event, _, _ := strings.Cut(scanner.Text(), ">>")
+			if hyprlandRegionEvents[event] {
+				notifyChanged(changed)
+			}
+		}
+	}()
+
+	return nil
+}
+
 type SwayRegions struct {
 }
 
@@ -217,33 +530,50 @@ type SwayOutput struct {
 	CurrentWorkspace string `json:"current_workspace"`
 }
 
+type SwayWorkspace struct {
+	Name    string
+	Output  string
+	Visible bool
+	Rect    SwayRect // The area of the output that is not covered by bars
+}
+
+type SwayWindowProperties struct {
+	Class string
+}
+
 type SwayNode struct {
-	Type          string
-	Name          string
-	Rect          SwayRect
-	WindowRect    SwayRect `json:"window_rect"`
-	Nodes         []SwayNode
-	FloatingNodes []SwayNode `json:"floating_nodes"`
+	ID               int
+	Pid              int
+	Type             string
+	Name             string
+	Layout           string
+	Visible          *bool                // Only set for containers of windows
+	Focus            []int                // The IDs of the children from most to least recently focused
+	AppID            string               `json:"app_id"`
+	WindowProperties SwayWindowProperties `json:"window_properties"`
+	Rect             SwayRect
+	WindowRect       SwayRect `json:"window_rect"`
+	Nodes            []SwayNode
+	FloatingNodes    []SwayNode `json:"floating_nodes"`
 }
 
-func (*SwayRegions) OutputRegions() (rs []Region) {
-	swaymsgPath, err := exec.LookPath("swaymsg")
-	if err != nil {
-		return
+// Class returns the app_id of wayland windows and the class of X11 windows
+func (n SwayNode) Class() string {
+	if n.AppID != "" {
+		return n.AppID
 	}
+	return n.WindowProperties.Class
+}
 
-	var stdout strings.Builder
-	swaymsg := exec.Command(swaymsgPath, "--raw", "-t", "get_outputs")
-	swaymsg.Stdout = &stdout
-	swaymsg.Stderr = os.Stderr
-
-	if err = swaymsg.Run(); err != nil {
+func (*SwayRegions) OutputRegions(ctx context.Context) (rs []Region, err error) {
+	ipc, err := DialI3IPC(ctx)
+	if err != nil {
 		return
 	}
+	defer ipc.Close()
 
 	var outputs []SwayOutput
-	decoder := json.NewDecoder(strings.NewReader(stdout.String()))
-	if err = decoder.Decode(&outputs); err != nil {
+	if err = ipc.RequestJSON(I3IPCGetOutputs, nil, &outputs); err != nil {
 		return
 	}
 
@@ -252,43 +582,173 @@ func (*SwayRegions) OutputRegions() (rs []Region) {
 		currentWorkspaces = append(currentWorkspaces, o.CurrentWorkspace)
 	}
 
-	stdout.Reset()
-	swaymsg = exec.Command(swaymsgPath, "--raw", "-t", "get_tree")
-	swaymsg.Stdout = &stdout
-	swaymsg.Stderr = os.Stderr
+	var tree SwayNode
+	if err = ipc.RequestJSON(I3IPCGetTree, nil, &tree); err != nil {
+		return
+	}
+
+	// Floating containers are added first in the order of their focus
+	swayTreeAddRegions(&rs, tree, currentWorkspaces, "", 0)
+	stackRegions(rs)
+
+	return
+}
+
+// CursorPos is not supported since sway does not report the position of
+// the cursor
+func (*SwayRegions) CursorPos(context.Context) (int, int, error) {
+	return 0, 0, ErrNotSupported
+}
 
-	if err = swaymsg.Run(); err != nil {
+func (*SwayRegions) Capabilities() RegionsCapabilities {
+	return CapabilityWatch | CapabilityWindowMetadata | CapabilityDecorations | CapabilityHierarchy
+}
+
+// UsableAreas returns the area of the visible workspaces, which excludes
+// the bars of sway and layer surfaces with an exclusive zone
+func (*SwayRegions) UsableAreas(ctx context.Context) (rs []Region, err error) {
+	ipc, err := DialI3IPC(ctx)
+	if err != nil {
 		return
 	}
+	defer ipc.Close()
 
-	decoder = json.NewDecoder(strings.NewReader(stdout.String()))
-	var tree SwayNode
-	if err = decoder.Decode(&tree); err != nil {
+	var workspaces []SwayWorkspace
+	if err = ipc.RequestJSON(I3IPCGetWorkspaces, nil, &workspaces); err != nil {
 		return
 	}
 
-	swayTreeAddRegions(&rs, tree, currentWorkspaces)
+	for _, ws := range workspaces {
+		if !ws.Visible {
+			continue
+		}
+
+		rs = append(rs, Region{
+			Geo: samure.Rect{
+				X: ws.Rect.X,
+				Y: ws.Rect.Y,
+				W: ws.Rect.Width,
+				H: ws.Rect.Height,
+			},
+			Name:      ws.Output,
+			Workspace: ws.Name,
+			Provider:  "sway",
+		})
+	}
 
 	return
 }
 
-func (*SwayRegions) CursorPos() (int, int, error) {
-	return 0, 0, errors.New("not implemented")
+func (*SwayRegions) Watch(ctx context.Context, changed chan<- struct{}) error {
+	ipc, err := DialI3IPC(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = ipc.Subscribe([]string{"window", "workspace", "output"}); err != nil {
+		ipc.Close()
+		return err
+	}
+
+	go func() {
+		defer close(changed)
+		defer context.AfterFunc(ctx, func() { ipc.Close() })()
+		defer ipc.Close()
+
+		for {
+			if _, _, err := ipc.ReceiveEvent(); err != nil {
+				return
+			}
+			notifyChanged(changed)
+		}
+	}()
+
+	return nil
+}
+
+// Geo returns the geometry of the container. The rect of a container
+// includes its title bar and borders and the window rect is relative to it.
+func (n SwayNode) Geo() samure.Rect {
+	if flags.RegionGeometry == "decorations" || n.WindowRect.Width == 0 || n.WindowRect.Height == 0 {
+		return samure.Rect{
+			X: n.Rect.X,
+			Y: n.Rect.Y,
+			W: n.Rect.Width,
+			H: n.Rect.Height,
+		}
+	}
+
+	return samure.Rect{
+		X: n.Rect.X + n.WindowRect.X,
+		Y: n.Rect.Y + n.WindowRect.Y,
+		W: n.WindowRect.Width,
+		H: n.WindowRect.Height,
+	}
 }
 
-func swayTreeAddRegions(rs *[]Region, n SwayNode, currentWorkspaces []string) {
+// visibleNodes returns the children of n that can be seen. Floating children
+// are ordered from top to bottom and only the focused child of tabbed and
+// stacked containers is visible.
+func (n SwayNode) visibleNodes() (nodes []SwayNode) {
+	floating := make([]SwayNode, 0, len(n.FloatingNodes))
+	for _, id := range n.Focus {
+		for _, child := range n.FloatingNodes {
+			if child.ID == id {
+				floating = append(floating, child)
+			}
+		}
+	}
+	for _, child := range n.FloatingNodes {
+		if !slices.Contains(n.Focus, child.ID) {
+			floating = append(floating, child)
+		}
+	}
+	nodes = append(nodes, floating...)
+
+	if (n.Layout == "tabbed" || n.Layout == "stacked") && len(n.Focus) != 0 {
+		for _, child := range n.Nodes {
+			if child.ID == n.Focus[0] {
+				nodes = append(nodes, child)
+			}
+		}
+		return
+	}
+
+	return append(nodes, n.Nodes...)
+}
+
+// swayTreeAddRegions adds the visible containers of the visible workspaces
+// below n. Containers that split their space between other containers are
+// added as well, so that they can be chosen by walking up from one of their
+// children.
+func swayTreeAddRegions(rs *[]Region, n SwayNode, currentWorkspaces []string, workspace string, parent int) {
+	if n.Visible != nil && !*n.Visible {
+		return
+	}
+
 	if n.Type == "con" || n.Type == "floating_con" {
 		*rs = append(*rs, Region{
-			Geo: samure.Rect{
-				X: n.Rect.X,
-				Y: n.Rect.Y,
-				W: n.Rect.Width,
-				H: n.Rect.Height,
-			},
-			Name: n.Name,
+			Geo:           n.Geo(),
+			Name:          n.Name,
+			Class:         n.Class(),
+			Workspace:     workspace,
+			Floating:      n.Type == "floating_con",
+			FloatingKnown: true,
+			Provider:      "sway",
+			Pid:           n.Pid,
+			Address:       strconv.Itoa(n.ID),
+			ID:            n.ID,
+			Parent:        parent,
+			Container:     len(n.Nodes) != 0 || len(n.FloatingNodes) != 0,
 		})
+
+		for _, child := range n.visibleNodes() {
+			swayTreeAddRegions(rs, child, currentWorkspaces, workspace, n.ID)
+		}
 	} else {
 		if n.Type == "workspace" {
+			workspace = n.Name
+
 			var isIn bool
 			for _, c := range currentWorkspaces {
 				if c == n.Name {
@@ -302,95 +762,202 @@ func swayTreeAddRegions(rs *[]Region, n SwayNode, currentWorkspaces []string) {
 			}
 		}
 
-		for _, child := range n.FloatingNodes {
-			swayTreeAddRegions(rs, child, currentWorkspaces)
-		}
-		for _, child := range n.Nodes {
-			swayTreeAddRegions(rs, child, currentWorkspaces)
+		for _, child := range n.visibleNodes() {
+			swayTreeAddRegions(rs, child, currentWorkspaces, workspace, parent)
 		}
 	}
 }
 
-type ArgumentRegions struct {
+// OutputsRegions uses the outputs as regions. They are not known until the
+// wayland context has been created and are set using SetOutputs.
+type OutputsRegions struct {
+	mutex   sync.Mutex
 	regions []Region
+	changed chan<- struct{}
 }
 
-func (a *ArgumentRegions) OutputRegions() []Region {
-	if len(a.regions) != 0 {
-		return a.regions
+func (o *OutputsRegions) SetOutputs(ctx samure.Context) {
+	var rs []Region
+	for i := 0; i < ctx.LenOutputs(); i++ {
+		rs = append(rs, Region{
+			Geo:      ctx.Output(i).Geo(),
+			Name:     ctx.Output(i).Name(),
+			Provider: "outputs",
+		})
 	}
 
-	var r Region
-	r.Name = "nil"
-	var this string
-	other := flags.RegionsArgument
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
 
-	for len(other) != 0 {
-		words := strings.SplitN(other, " ", 2)
-		if len(words) != 2 {
-			return a.regions
-		}
-		this, other = strings.TrimSpace(words[0]), strings.TrimSpace(words[1])
+	o.regions = rs
+	if o.changed != nil {
+		notifyChanged(o.changed)
+	}
+}
 
-		r.Name = this
+func (o *OutputsRegions) OutputRegions(context.Context) ([]Region, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.regions, nil
+}
+
+func (*OutputsRegions) CursorPos(context.Context) (int, int, error) {
+	return 0, 0, ErrNotSupported
+}
 
-		words = strings.SplitN(other, ",", 2)
-		if len(words) != 2 {
-			return a.regions
+func (*OutputsRegions) Capabilities() RegionsCapabilities {
+	return CapabilityWatch
+}
+
+func (o *OutputsRegions) Watch(ctx context.Context, changed chan<- struct{}) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.changed = changed
+
+	context.AfterFunc(ctx, func() {
+		o.mutex.Lock()
+		defer o.mutex.Unlock()
+		if o.changed == changed {
+			close(changed)
+			o.changed = nil
 		}
-		this, other = strings.TrimSpace(words[0]), strings.TrimSpace(words[1])
+	})
+	return nil
+}
 
-		x, err := strconv.ParseInt(this, 10, 64)
+// MultiRegions combines the regions of multiple providers. The regions of
+// earlier providers take priority over the ones of later providers.
+type MultiRegions struct {
+	providers []Regions
+}
+
+// OutputRegions returns the regions of all providers. The stacking order of
+// the regions is moved so that the regions of earlier providers are on top
+// of the ones of later providers. Providers that fail are skipped and their
+// errors are returned together with the regions of the other providers.
+func (m *MultiRegions) OutputRegions(ctx context.Context) (rs []Region, err error) {
+	var errs []error
+	var base int
+	for i := len(m.providers) - 1; i >= 0; i-- {
+		prs, err := m.providers[i].OutputRegions(ctx)
 		if err != nil {
-			return a.regions
+			errs = append(errs, err)
 		}
-		r.Geo.X = int(x)
-
-		words = strings.SplitN(other, " ", 2)
-		if len(words) != 2 {
-			return a.regions
+		if len(prs) == 0 {
+			continue
 		}
-		this, other = strings.TrimSpace(words[0]), strings.TrimSpace(words[1])
+		prs = slices.Clone(prs)
 
-		y, err := strconv.ParseInt(this, 10, 64)
-		if err != nil {
-			return a.regions
+		minZ, maxZ := prs[0].Z, prs[0].Z
+		for _, r := range prs {
+			minZ = min(minZ, r.Z)
+			maxZ = max(maxZ, r.Z)
 		}
-		r.Geo.Y = int(y)
+		for j := range prs {
+			prs[j].Z += base - minZ
+		}
+		base += maxZ - minZ + 1
+
+		rs = append(prs, rs...)
+	}
+
+	// The errors are collected from the last to the first provider
+	slices.Reverse(errs)
+	return rs, errors.Join(errs...)
+}
 
-		words = strings.SplitN(other, "x", 2)
-		if len(words) != 2 {
-			return a.regions
+// CursorPos returns the position of the cursor from the first provider that
+// is able to retrieve it
+func (m *MultiRegions) CursorPos(ctx context.Context) (int, int, error) {
+	var errs []error
+	for _, p := range m.providers {
+		if !p.Capabilities().Has(CapabilityCursor) {
+			continue
 		}
-		this, other = strings.TrimSpace(words[0]), strings.TrimSpace(words[1])
 
-		w, err := strconv.ParseUint(this, 10, 64)
-		if err != nil {
-			return a.regions
+		x, y, err := p.CursorPos(ctx)
+		if err == nil {
+			return x, y, nil
 		}
-		r.Geo.W = int(w)
+		errs = append(errs, err)
+	}
 
-		words = strings.SplitN(other, " ", 2)
-		this = strings.TrimSpace(words[0])
+	if len(errs) == 0 {
+		return 0, 0, ErrNotSupported
+	}
+	return 0, 0, errors.Join(errs...)
+}
 
-		h, err := strconv.ParseUint(this, 10, 64)
-		if err != nil {
-			return a.regions
-		}
-		r.Geo.H = int(h)
+// Capabilities returns what any of the providers supports. The regions can
+// only be watched if all providers support it.
+func (m *MultiRegions) Capabilities() (c RegionsCapabilities) {
+	watch := true
+	for _, p := range m.providers {
+		c |= p.Capabilities()
+		watch = watch && p.Capabilities().Has(CapabilityWatch)
+	}
+
+	if !watch {
+		c &^= CapabilityWatch
+	}
+	return
+}
 
-		a.regions = append(a.regions, r)
+func (m *MultiRegions) Close() error {
+	var errs []error
+	for _, p := range m.providers {
+		errs = append(errs, closeRegions(p))
+	}
+	return errors.Join(errs...)
+}
 
-		if len(words) == 2 {
-			other = strings.TrimSpace(words[1])
-		} else {
-			return a.regions
+// Watch watches the regions of all providers. If one of them can not be
+// watched anymore all regions need to be polled, so the watchers of the
+// other providers are stopped as well.
+func (m *MultiRegions) Watch(ctx context.Context, changed chan<- struct{}) error {
+	for _, p := range m.providers {
+		if _, ok := p.(RegionsWatcher); !ok {
+			return fmt.Errorf("%T can not be watched", p)
 		}
 	}
 
-	return a.regions
-}
+	var mutex sync.Mutex
+	var closed bool
+	watchCtx, cancel := context.WithCancel(ctx)
+	closeChanged := func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if !closed {
+			closed = true
+			close(changed)
+			cancel()
+		}
+	}
 
-func (*ArgumentRegions) CursorPos() (int, int, error) {
-	return 0, 0, errors.New("not implemented")
+	for _, p := range m.providers {
+		providerChanged := make(chan struct{}, 1)
+		if err := p.(RegionsWatcher).Watch(watchCtx, providerChanged); err != nil {
+			// Stop the providers that are already being watched without
+			// closing changed
+			mutex.Lock()
+			closed = true
+			mutex.Unlock()
+			cancel()
+			return err
+		}
+
+		go func() {
+			for range providerChanged {
+				mutex.Lock()
+				if !closed {
+					notifyChanged(changed)
+				}
+				mutex.Unlock()
+			}
+
+			closeChanged()
+		}()
+	}
+
+	return nil
 }
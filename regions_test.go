@@ -26,7 +26,16 @@
 
 package main
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	samure "github.com/Samudevv/samurai-render-go"
+)
 
 func TestRegions(t *testing.T) {
 	regions := DetectRegions()
@@ -35,6 +44,220 @@ func TestRegions(t *testing.T) {
 		t.Fatal("Could not detect what compositor is running")
 	}
 
-	t.Log(regions.OutputRegions())
+	t.Log(regions.OutputRegions(context.Background()))
 	t.Fail()
 }
+
+func TestMultiRegionsStacking(t *testing.T) {
+	m := MultiRegions{providers: []Regions{
+		&ArgumentRegions{regions: []Region{
+			{Geo: samure.Rect{W: 10, H: 10}, Name: "arg"},
+		}},
+		&ArgumentRegions{regions: []Region{
+			{Geo: samure.Rect{W: 100, H: 100}, Name: "top", Z: 5},
+			{Geo: samure.Rect{W: 100, H: 100}, Name: "bottom", Z: -3},
+		}},
+	}}
+
+	// Retrieving the regions twice must not move them twice
+	m.OutputRegions(context.Background())
+	rs, err := m.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sortRegions(rs)
+
+	names := []string{"arg", "top", "bottom"}
+	for i := range names {
+		if rs[i].Name != names[i] {
+			t.Errorf("expected region %d to be \"%s\" but got \"%s\"", i, names[i], rs[i].Name)
+		}
+	}
+	if rs[0].Z <= rs[1].Z {
+		t.Errorf("the regions of the first provider need to be on top: %v", rs)
+	}
+}
+
+func TestRegionAt(t *testing.T) {
+	var a App
+	a.setRegions([]Region{
+		{Geo: samure.Rect{W: 1920, H: 1080}, Name: "output", Z: 1},
+		{Geo: samure.Rect{X: 100, Y: 100, W: 200, H: 200}, Name: "window", Z: 0},
+		{Geo: samure.Rect{W: 1920, H: 1080}, Name: "container", Z: 2, Container: true},
+	})
+
+	if r := a.regionAt(150, 150); r.Name != "output" {
+		t.Errorf("expected the top most region but got \"%s\"", r.Name)
+	}
+
+	flags.RegionPick = "smallest"
+	defer func() { flags.RegionPick = "" }()
+
+	if r := a.regionAt(150, 150); r.Name != "window" {
+		t.Errorf("expected the smallest region but got \"%s\"", r.Name)
+	}
+	if r := a.regionAt(1000, 1000); r.Name != "output" {
+		t.Errorf("expected the only region but got \"%s\"", r.Name)
+	}
+}
+
+// countingRegions returns a new region every second time it is asked
+type countingRegions struct {
+	calls atomic.Int32
+}
+
+func (c *countingRegions) OutputRegions(context.Context) ([]Region, error) {
+	n := int(c.calls.Add(1))
+	return []Region{{Geo: samure.Rect{W: 100, H: 100}, Name: strconv.Itoa(n / 2)}}, nil
+}
+
+func (*countingRegions) CursorPos(context.Context) (int, int, error) {
+	return 50, 60, nil
+}
+
+func (*countingRegions) Capabilities() RegionsCapabilities {
+	return CapabilityCursor
+}
+
+func TestRegionsWorker(t *testing.T) {
+	flags.RegionsRefresh = time.Millisecond
+	defer func() { flags.RegionsRefresh = 0 }()
+
+	a := App{regionsObj: &countingRegions{}}
+	a.startRegionsWorker()
+
+	var names []string
+	for len(names) < 3 {
+		rs := <-a.regionsUpdate
+		names = append(names, rs[0].Name)
+	}
+
+	// Regions that did not change are not handed over again
+	for i := 1; i < len(names); i++ {
+		if names[i] == names[i-1] {
+			t.Errorf("the same regions have been handed over twice: %v", names)
+		}
+	}
+
+	a.stopRegionsWorker()
+	for range a.regionsUpdate {
+	}
+
+	a.fetchCursorPos()
+	if pos := <-a.cursorUpdate; pos != [2]int{50, 60} {
+		t.Errorf("wrong cursor position: %v", pos)
+	}
+}
+
+// failingRegions can never retrieve any regions
+type failingRegions struct{}
+
+func (failingRegions) OutputRegions(context.Context) ([]Region, error) {
+	return nil, errors.New("compositor is not reachable")
+}
+
+func (failingRegions) CursorPos(context.Context) (int, int, error) {
+	return 0, 0, ErrNotSupported
+}
+
+func (failingRegions) Capabilities() RegionsCapabilities {
+	return 0
+}
+
+func TestRegionsWorkerFailure(t *testing.T) {
+	flags.RegionsRefresh = time.Millisecond
+	defer func() { flags.RegionsRefresh = 0 }()
+
+	a := App{regionsObj: failingRegions{}}
+	a.startRegionsWorker()
+
+	// The regions are retried until the worker is stopped
+	select {
+	case rs, ok := <-a.regionsUpdate:
+		t.Fatalf("expected the worker to keep trying but got %v, %t", rs, ok)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	a.stopRegionsWorker()
+	if rs, ok := <-a.regionsUpdate; ok {
+		t.Errorf("expected no regions but got %v", rs)
+	}
+
+	// The cursor position is not requested from providers that do not support it
+	a.fetchCursorPos()
+	if pos, ok := <-a.cursorUpdate; ok {
+		t.Errorf("expected no cursor position but got %v", pos)
+	}
+}
+
+// slowRegions fails to retrieve its regions the first time it is asked
+type slowRegions struct {
+	calls atomic.Int32
+}
+
+func (s *slowRegions) OutputRegions(context.Context) ([]Region, error) {
+	if s.calls.Add(1) == 1 {
+		return nil, errors.New("regions are not ready yet")
+	}
+	return []Region{{Geo: samure.Rect{W: 100, H: 100}, Name: "slow"}}, nil
+}
+
+func (*slowRegions) CursorPos(context.Context) (int, int, error) {
+	return 0, 0, ErrNotSupported
+}
+
+func (*slowRegions) Capabilities() RegionsCapabilities {
+	return 0
+}
+
+func TestRegionsWorkerRetry(t *testing.T) {
+	flags.RegionsRefresh = time.Millisecond
+	defer func() { flags.RegionsRefresh = 0 }()
+
+	a := App{regionsObj: &slowRegions{}}
+	a.startRegionsWorker()
+	defer a.stopRegionsWorker()
+
+	rs, ok := <-a.regionsUpdate
+	if !ok || len(rs) != 1 || rs[0].Name != "slow" {
+		t.Errorf("expected the regions of the second try but got %v", rs)
+	}
+}
+
+func (failingRegions) Watch(context.Context, chan<- struct{}) error {
+	return errors.New("compositor is not reachable")
+}
+
+func TestMultiRegionsWatchFailure(t *testing.T) {
+	outputs := &OutputsRegions{}
+	m := MultiRegions{providers: []Regions{outputs, failingRegions{}}}
+
+	changed := make(chan struct{}, 1)
+	if err := m.Watch(context.Background(), changed); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// The watcher of the outputs is stopped in the background
+	for i := 0; ; i++ {
+		outputs.mutex.Lock()
+		watching := outputs.changed != nil
+		outputs.mutex.Unlock()
+
+		if !watching {
+			break
+		}
+		if i == 100 {
+			t.Fatal("the outputs are still being watched")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// changed belongs to the caller after an error
+	select {
+	case _, ok := <-changed:
+		if !ok {
+			t.Error("changed has been closed")
+		}
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,214 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Message types of the i3-ipc protocol which is used by sway and i3
+const (
+	I3IPCRunCommand    = 0
+	I3IPCGetWorkspaces = 1
+	I3IPCSubscribe     = 2
+	I3IPCGetOutputs    = 3
+	I3IPCGetTree       = 4
+
+	I3IPCEventMask = 0x80000000
+
+	I3IPCMagic   = "i3-ipc"
+	I3IPCTimeout = time.Second
+)
+
+// I3IPC is a connection to the IPC socket of sway or i3. Every message
+// consists of the magic string, the length and the type of the payload
+// and the payload itself.
+type I3IPC struct {
+	conn net.Conn
+	ctx  context.Context
+}
+
+// i3SocketPath returns the path of the IPC socket using $SWAYSOCK or
+// $I3SOCK. If both are not set i3 itself is asked.
+func i3SocketPath(ctx context.Context) (string, error) {
+	if socketPath := os.Getenv("SWAYSOCK"); socketPath != "" {
+		return socketPath, nil
+	}
+	if socketPath := os.Getenv("I3SOCK"); socketPath != "" {
+		return socketPath, nil
+	}
+
+	i3Path, err := exec.LookPath("i3")
+	if err != nil {
+		return "", errors.New("SWAYSOCK and I3SOCK are not set")
+	}
+
+	var stdout strings.Builder
+	i3 := exec.CommandContext(ctx, i3Path, "--get-socketpath")
+	i3.Stdout = &stdout
+	if err = i3.Run(); err != nil {
+		return "", fmt.Errorf("could not get socket path of i3: %w", err)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// DialI3IPC connects to sway or i3. Requests are interrupted once ctx is
+// done.
+func DialI3IPC(ctx context.Context) (*I3IPC, error) {
+	socketPath, err := i3SocketPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := dialContext(ctx, socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &I3IPC{conn: conn, ctx: ctx}, nil
+}
+
+func (c *I3IPC) Close() error {
+	return c.conn.Close()
+}
+
+func (c *I3IPC) send(msgType uint32, payload []byte) error {
+	msg := make([]byte, 0, len(I3IPCMagic)+8+len(payload))
+	msg = append(msg, I3IPCMagic...)
+	msg = binary.NativeEndian.AppendUint32(msg, uint32(len(payload)))
+	msg = binary.NativeEndian.AppendUint32(msg, msgType)
+	msg = append(msg, payload...)
+
+	_, err := c.conn.Write(msg)
+	return err
+}
+
+func (c *I3IPC) receive() (uint32, []byte, error) {
+	header := make([]byte, len(I3IPCMagic)+8)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return 0, nil, err
+	}
+
+	if !bytes.Equal(header[:len(I3IPCMagic)], []byte(I3IPCMagic)) {
+		return 0, nil, errors.New("invalid i3-ipc magic")
+	}
+
+	length := binary.NativeEndian.Uint32(header[len(I3IPCMagic):])
+	msgType := binary.NativeEndian.Uint32(header[len(I3IPCMagic)+4:])
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.conn, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return msgType, payload, nil
+}
+
+// Request sends a message and waits for the reply to it
+func (c *I3IPC) Request(msgType uint32, payload []byte) ([]byte, error) {
+	stop := bindConn(c.ctx, c.conn, I3IPCTimeout)
+	defer func() {
+		stop()
+		c.conn.SetDeadline(time.Time{})
+	}()
+
+	if err := c.send(msgType, payload); err != nil {
+		return nil, err
+	}
+
+	replyType, reply, err := c.receive()
+	if err != nil {
+		return nil, err
+	}
+	if replyType != msgType {
+		return nil, fmt.Errorf("expected reply of type %d but got %d", msgType, replyType)
+	}
+
+	return reply, nil
+}
+
+// RequestJSON sends a message and decodes the reply into v
+func (c *I3IPC) RequestJSON(msgType uint32, payload []byte, v interface{}) error {
+	reply, err := c.Request(msgType, payload)
+	if err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(reply, v); err != nil {
+		return fmt.Errorf("invalid reply to message of type %d: %w", msgType, err)
+	}
+
+	return nil
+}
+
+// Subscribe subscribes to the given events. Afterwards the events can be
+// received using ReceiveEvent.
+func (c *I3IPC) Subscribe(events []string) error {
+	payload, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	var reply struct {
+		Success bool
+	}
+	if err = c.RequestJSON(I3IPCSubscribe, payload, &reply); err != nil {
+		return err
+	}
+	if !reply.Success {
+		return fmt.Errorf("failed to subscribe to %v", events)
+	}
+
+	return nil
+}
+
+// ReceiveEvent blocks until an event has been received and returns its type
+// without the event mask
+func (c *I3IPC) ReceiveEvent() (uint32, []byte, error) {
+	for {
+		msgType, payload, err := c.receive()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if msgType&I3IPCEventMask != 0 {
+			return msgType &^ I3IPCEventMask, payload, nil
+		}
+	}
+}
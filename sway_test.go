@@ -0,0 +1,241 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	samure "github.com/Samudevv/samurai-render-go"
+)
+
+const swayTestTree = `{
+	"type": "root", "name": "root",
+	"nodes": [{
+		"type": "output", "name": "DP-1",
+		"nodes": [
+			{
+				"type": "workspace", "name": "1",
+				"nodes": [
+					{"type": "con", "name": "terminal", "rect": {"x": 0, "y": 0, "width": 960, "height": 1080}, "window_rect": {"x": 2, "y": 2, "width": 956, "height": 1076}},
+					{"type": "con", "name": "editor", "rect": {"x": 960, "y": 0, "width": 960, "height": 1080}, "window_rect": {"x": 2, "y": 2, "width": 956, "height": 1076}}
+				],
+				"floating_nodes": [
+					{"type": "floating_con", "name": "popup", "rect": {"x": 100, "y": 100, "width": 200, "height": 100}}
+				]
+			},
+			{
+				"type": "workspace", "name": "2",
+				"nodes": [
+					{"type": "con", "name": "invisible", "rect": {"x": 0, "y": 0, "width": 1920, "height": 1080}}
+				]
+			}
+		]
+	}]
+}`
+
+// startI3IPCServer creates a stand-in for the IPC socket of sway that replies
+// to every message with the given replies
+func startI3IPCServer(t *testing.T, replies map[uint32]string) {
+	socketPath := filepath.Join(t.TempDir(), "sway.sock")
+	t.Setenv("SWAYSOCK", socketPath)
+
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				server := I3IPC{conn: conn}
+				defer server.Close()
+
+				for {
+					msgType, _, err := server.receive()
+					if err != nil {
+						return
+					}
+					if err = server.send(msgType, []byte(replies[msgType])); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+}
+
+func TestSwayRegions(t *testing.T) {
+	startI3IPCServer(t, map[uint32]string{
+		I3IPCGetOutputs: `[{"name": "DP-1", "current_workspace": "1"}]`,
+		I3IPCGetTree:    swayTestTree,
+	})
+
+	var s SwayRegions
+	rs, err := s.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"popup", "terminal", "editor"}
+	if len(rs) != len(names) {
+		t.Fatalf("expected %d regions but got %d: %v", len(names), len(rs), rs)
+	}
+	for i := range names {
+		if rs[i].Name != names[i] {
+			t.Errorf("expected region %d to be \"%s\" but got \"%s\"", i, names[i], rs[i].Name)
+		}
+	}
+}
+
+func TestSwayRegionsHierarchy(t *testing.T) {
+	startI3IPCServer(t, map[uint32]string{
+		I3IPCGetOutputs: `[{"name": "DP-1", "current_workspace": "1"}]`,
+		I3IPCGetTree: `{
+			"id": 1, "type": "root", "name": "root",
+			"nodes": [{
+				"id": 3, "type": "output", "name": "DP-1",
+				"nodes": [{
+					"id": 4, "type": "workspace", "name": "1",
+					"nodes": [
+						{"id": 5, "type": "con", "name": "browser", "rect": {"x": 0, "y": 0, "width": 960, "height": 1080}},
+						{
+							"id": 6, "type": "con", "rect": {"x": 960, "y": 0, "width": 960, "height": 1080},
+							"nodes": [
+								{"id": 7, "type": "con", "name": "editor", "rect": {"x": 960, "y": 0, "width": 960, "height": 540}},
+								{"id": 8, "type": "con", "name": "terminal", "rect": {"x": 960, "y": 540, "width": 960, "height": 540}}
+							]
+						}
+					]
+				}]
+			}]
+		}`,
+	})
+
+	var s SwayRegions
+	rs, err := s.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		name      string
+		id        int
+		parent    int
+		container bool
+	}{
+		{"browser", 5, 0, false},
+		{"", 6, 0, true},
+		{"editor", 7, 6, false},
+		{"terminal", 8, 6, false},
+	}
+	if len(rs) != len(expected) {
+		t.Fatalf("expected %d regions but got %d: %v", len(expected), len(rs), rs)
+	}
+	for i, e := range expected {
+		if rs[i].Name != e.name || rs[i].ID != e.id || rs[i].Parent != e.parent || rs[i].Container != e.container {
+			t.Errorf("expected region %d to be %+v but got %+v", i, e, rs[i])
+		}
+	}
+}
+
+func TestSwayRegionsVisibility(t *testing.T) {
+	startI3IPCServer(t, map[uint32]string{
+		I3IPCGetOutputs: `[{"name": "DP-1", "current_workspace": "1"}]`,
+		I3IPCGetTree: `{
+			"id": 1, "type": "root", "name": "root",
+			"nodes": [{
+				"id": 3, "type": "output", "name": "DP-1",
+				"nodes": [{
+					"id": 4, "type": "workspace", "name": "1", "focus": [9, 6, 5, 10],
+					"nodes": [
+						{
+							"id": 5, "type": "con", "layout": "tabbed", "focus": [7, 6], "rect": {"x": 0, "y": 0, "width": 1920, "height": 1080},
+							"nodes": [
+								{"id": 6, "type": "con", "name": "hidden tab", "visible": false, "rect": {"x": 0, "y": 30, "width": 1920, "height": 1050}},
+								{"id": 7, "type": "con", "name": "shown tab", "visible": true, "rect": {"x": 0, "y": 30, "width": 1920, "height": 1050}}
+							]
+						}
+					],
+					"floating_nodes": [
+						{"id": 10, "type": "floating_con", "name": "below", "visible": true, "rect": {"x": 100, "y": 100, "width": 200, "height": 100}},
+						{"id": 9, "type": "floating_con", "name": "above", "visible": true, "rect": {"x": 150, "y": 150, "width": 200, "height": 100}}
+					]
+				}]
+			}]
+		}`,
+	})
+
+	var s SwayRegions
+	rs, err := s.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"above", "below", "", "shown tab"}
+	if len(rs) != len(names) {
+		t.Fatalf("expected %d regions but got %d: %v", len(names), len(rs), rs)
+	}
+	for i := range names {
+		if rs[i].Name != names[i] {
+			t.Errorf("expected region %d to be \"%s\" but got \"%s\"", i, names[i], rs[i].Name)
+		}
+	}
+}
+
+func TestSwayUsableAreas(t *testing.T) {
+	startI3IPCServer(t, map[uint32]string{
+		I3IPCGetWorkspaces: `[
+			{"name": "1", "output": "DP-1", "visible": true, "rect": {"x": 0, "y": 30, "width": 1920, "height": 1050}},
+			{"name": "2", "output": "DP-1", "visible": false, "rect": {"x": 0, "y": 30, "width": 1920, "height": 1050}},
+			{"name": "3", "output": "HDMI-A-1", "visible": true, "rect": {"x": 1920, "y": 0, "width": 1280, "height": 1024}}
+		]`,
+	})
+
+	var s SwayRegions
+	rs, err := s.UsableAreas(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Region{
+		{Geo: samure.Rect{X: 0, Y: 30, W: 1920, H: 1050}, Name: "DP-1", Workspace: "1", Provider: "sway"},
+		{Geo: samure.Rect{X: 1920, Y: 0, W: 1280, H: 1024}, Name: "HDMI-A-1", Workspace: "3", Provider: "sway"},
+	}
+	if !slices.Equal(rs, expected) {
+		t.Errorf("expected %v but got %v", expected, rs)
+	}
+}
@@ -0,0 +1,321 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	samure "github.com/Samudevv/samurai-render-go"
+)
+
+const WayfireTimeout = time.Second
+
+// Events of Wayfire after which the windows on the screen could have changed
+var wayfireRegionEvents = []string{
+	"view-mapped",
+	"view-unmapped",
+	"view-geometry-changed",
+	"view-minimized",
+	"view-focused",
+	"view-wset-changed",
+	"view-workspace-changed",
+	"output-wset-changed",
+	"wset-workspace-changed",
+}
+
+type WayfireRegions struct {
+}
+
+type WayfireGeometry struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+type WayfireOutput struct {
+	ID       int
+	Name     string
+	Geometry WayfireGeometry
+}
+
+type WayfireView struct {
+	ID                 int
+	Pid                int
+	Title              string
+	AppID              string `json:"app-id"`
+	Geometry           WayfireGeometry
+	BaseGeometry       *WayfireGeometry `json:"base-geometry"`
+	OutputID           int              `json:"output-id"`
+	Role               string
+	Type               string
+	Layer              string
+	Mapped             bool
+	Minimized          bool
+	TiledEdges         int    `json:"tiled-edges"`
+	LastFocusTimestamp uint64 `json:"last-focus-timestamp"`
+}
+
+// WayfireIPC is a connection to the socket of the ipc plugin of Wayfire.
+// Every message is json prefixed with its length.
+type WayfireIPC struct {
+	conn net.Conn
+	ctx  context.Context
+}
+
+// DialWayfireIPC connects to Wayfire. Requests are interrupted once ctx is
+// done.
+func DialWayfireIPC(ctx context.Context) (*WayfireIPC, error) {
+	socketPath := os.Getenv("WAYFIRE_SOCKET")
+	if socketPath == "" {
+		return nil, errors.New("WAYFIRE_SOCKET is not set")
+	}
+
+	conn, err := dialContext(ctx, socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WayfireIPC{conn: conn, ctx: ctx}, nil
+}
+
+func (c *WayfireIPC) Close() error {
+	return c.conn.Close()
+}
+
+func (c *WayfireIPC) send(msg []byte) error {
+	buf := binary.LittleEndian.AppendUint32(nil, uint32(len(msg)))
+	buf = append(buf, msg...)
+
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+func (c *WayfireIPC) receive() ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return nil, err
+	}
+
+	msg := make([]byte, binary.LittleEndian.Uint32(header))
+	if _, err := io.ReadFull(c.conn, msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// Request calls a method of the ipc plugin and decodes the response into v
+func (c *WayfireIPC) Request(method string, data interface{}, v interface{}) error {
+	if data == nil {
+		data = struct{}{}
+	}
+
+	msg, err := json.Marshal(struct {
+		Method string      `json:"method"`
+		Data   interface{} `json:"data"`
+	}{method, data})
+	if err != nil {
+		return err
+	}
+
+	stop := bindConn(c.ctx, c.conn, WayfireTimeout)
+	defer func() {
+		stop()
+		c.conn.SetDeadline(time.Time{})
+	}()
+
+	if err = c.send(msg); err != nil {
+		return err
+	}
+
+	response, err := c.receive()
+	if err != nil {
+		return err
+	}
+
+	// Errors are reported as an object with an error field
+	var wayfireErr struct {
+		Error *string
+	}
+	if json.Unmarshal(response, &wayfireErr) == nil && wayfireErr.Error != nil {
+		return fmt.Errorf("wayfire: %s: %s", method, *wayfireErr.Error)
+	}
+
+	if err = json.Unmarshal(response, v); err != nil {
+		return fmt.Errorf("invalid response to \"%s\": %w", method, err)
+	}
+
+	return nil
+}
+
+func (*WayfireRegions) OutputRegions(ctx context.Context) (rs []Region, err error) {
+	ipc, err := DialWayfireIPC(ctx)
+	if err != nil {
+		return
+	}
+	defer ipc.Close()
+
+	var outputs []WayfireOutput
+	if err = ipc.Request("window-rules/list-outputs", nil, &outputs); err != nil {
+		return
+	}
+
+	var views []WayfireView
+	if err = ipc.Request("window-rules/list-views", nil, &views); err != nil {
+		return
+	}
+
+	outputGeos := make(map[int]WayfireGeometry)
+	for _, o := range outputs {
+		outputGeos[o.ID] = o.Geometry
+	}
+
+	// The most recently focused views are on top
+	sort.SliceStable(views, func(i, j int) bool {
+		return views[i].LastFocusTimestamp > views[j].LastFocusTimestamp
+	})
+
+	for _, v := range views {
+		if !v.Mapped || v.Minimized || v.Pid == os.Getpid() {
+			continue
+		}
+
+		// Layer surfaces like panels are views of the desktop environment
+		isLayer := v.Role == "desktop-environment" && v.Type != "background"
+		if v.Role != "toplevel" && !(flags.Layers && isLayer) {
+			continue
+		}
+
+		o, ok := outputGeos[v.OutputID]
+		if !ok {
+			continue
+		}
+
+		// The geometry of views is relative to the current workspace of their
+		// output, so views on other workspaces are outside of the output
+		outputLocal := samure.Rect{W: o.Width, H: o.Height}
+		if !outputLocal.RectInOutput(v.Geometry.X, v.Geometry.Y, v.Geometry.Width, v.Geometry.Height) {
+			continue
+		}
+
+		// The geometry includes the decorations and the base geometry does not
+		geo := v.Geometry
+		if flags.RegionGeometry != "decorations" && v.BaseGeometry != nil {
+			geo = *v.BaseGeometry
+		}
+
+		rs = append(rs, Region{
+			Geo: samure.Rect{
+				X: o.X + geo.X,
+				Y: o.Y + geo.Y,
+				W: geo.Width,
+				H: geo.Height,
+			},
+			Name:          v.Title,
+			Class:         v.AppID,
+			Floating:      v.TiledEdges == 0,
+			FloatingKnown: true,
+			Provider:      "wayfire",
+			Pid:           v.Pid,
+			Address:       strconv.Itoa(v.ID),
+		})
+	}
+
+	stackRegions(rs)
+
+	return
+}
+
+func (*WayfireRegions) CursorPos(ctx context.Context) (int, int, error) {
+	ipc, err := DialWayfireIPC(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer ipc.Close()
+
+	var cursor struct {
+		Pos *struct {
+			X float64
+			Y float64
+		}
+	}
+	if err = ipc.Request("window-rules/get_cursor_position", nil, &cursor); err != nil {
+		return 0, 0, err
+	}
+	if cursor.Pos == nil {
+		return 0, 0, ErrNotSupported
+	}
+
+	return int(cursor.Pos.X), int(cursor.Pos.Y), nil
+}
+
+func (*WayfireRegions) Capabilities() RegionsCapabilities {
+	return CapabilityCursor | CapabilityWatch | CapabilityClass | CapabilityFloating | CapabilityPid | CapabilityAddress | CapabilityLayers | CapabilityDecorations
+}
+
+func (*WayfireRegions) Watch(ctx context.Context, changed chan<- struct{}) error {
+	ipc, err := DialWayfireIPC(ctx)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Result string
+	}
+	if err = ipc.Request("window-rules/events/watch", map[string]interface{}{
+		"events": wayfireRegionEvents,
+	}, &result); err != nil {
+		ipc.Close()
+		return err
+	}
+
+	go func() {
+		defer close(changed)
+		defer context.AfterFunc(ctx, func() { ipc.Close() })()
+		defer ipc.Close()
+
+		for {
+			if _, err := ipc.receive(); err != nil {
+				return
+			}
+			notifyChanged(changed)
+		}
+	}()
+
+	return nil
+}
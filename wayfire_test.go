@@ -0,0 +1,127 @@
+/***********************************************************************************
+ *                         This file is part of samurai-select
+ *                    https://github.com/Samudevv/samurai-select
+ ***********************************************************************************
+ * Copyright (c) 2023 Jonas Pucher
+ *
+ * This software is provided ‘as-is’, without any express or implied
+ * warranty. In no event will the authors be held liable for any damages
+ * arising from the use of this software.
+ *
+ * Permission is granted to anyone to use this software for any purpose,
+ * including commercial applications, and to alter it and redistribute it
+ * freely, subject to the following restrictions:
+ *
+ * 1. The origin of this software must not be misrepresented; you must not
+ * claim that you wrote the original software. If you use this software
+ * in a product, an acknowledgment in the product documentation would be
+ * appreciated but is not required.
+ *
+ * 2. Altered source versions must be plainly marked as such, and must not be
+ * misrepresented as being the original software.
+ *
+ * 3. This notice may not be removed or altered from any source
+ * distribution.
+ ************************************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+// startWayfireServer creates a stand-in for the socket of the ipc plugin of
+// Wayfire that answers calls of methods with the given responses
+func startWayfireServer(t *testing.T, responses map[string]string) {
+	socketPath := filepath.Join(t.TempDir(), "wayfire.sock")
+	t.Setenv("WAYFIRE_SOCKET", socketPath)
+
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				server := WayfireIPC{conn: conn}
+				defer server.Close()
+
+				for {
+					msg, err := server.receive()
+					if err != nil {
+						return
+					}
+
+					var request struct {
+						Method string
+					}
+					json.Unmarshal(msg, &request)
+
+					response, ok := responses[request.Method]
+					if !ok {
+						response = `{"error": "No such method found!"}`
+					}
+					if err = server.send([]byte(response)); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+}
+
+func TestWayfireRegions(t *testing.T) {
+	startWayfireServer(t, map[string]string{
+		"window-rules/list-outputs": `[
+			{"id": 1, "name": "DP-1", "geometry": {"x": 0, "y": 0, "width": 1920, "height": 1080}},
+			{"id": 2, "name": "DP-2", "geometry": {"x": 1920, "y": 0, "width": 1920, "height": 1080}}
+		]`,
+		"window-rules/list-views": `[
+			{"id": 1, "title": "old", "role": "toplevel", "mapped": true, "output-id": 1, "last-focus-timestamp": 10,
+			 "geometry": {"x": 10, "y": 10, "width": 500, "height": 400}},
+			{"id": 2, "title": "recent", "role": "toplevel", "mapped": true, "output-id": 2, "last-focus-timestamp": 20,
+			 "geometry": {"x": 100, "y": 200, "width": 500, "height": 400}},
+			{"id": 3, "title": "minimized", "role": "toplevel", "mapped": true, "minimized": true, "output-id": 1,
+			 "geometry": {"x": 10, "y": 10, "width": 500, "height": 400}},
+			{"id": 4, "title": "other workspace", "role": "toplevel", "mapped": true, "output-id": 1,
+			 "geometry": {"x": 1930, "y": 10, "width": 500, "height": 400}},
+			{"id": 5, "title": "panel", "role": "desktop-environment", "mapped": true, "output-id": 1,
+			 "geometry": {"x": 0, "y": 0, "width": 1920, "height": 30}}
+		]`,
+		"window-rules/get_cursor_position": `{"pos": {"x": 2000.5, "y": 300.0}}`,
+	})
+
+	var w WayfireRegions
+	rs, err := w.OutputRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 {
+		t.Fatalf("expected 2 regions but got %d: %v", len(rs), rs)
+	}
+	if rs[0].Name != "recent" || rs[0].Geo.X != 2020 || rs[0].Geo.Y != 200 {
+		t.Errorf("wrong region on top: %v", rs[0])
+	}
+	if rs[1].Name != "old" {
+		t.Errorf("wrong region below: %v", rs[1])
+	}
+
+	x, y, err := w.CursorPos(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x != 2000 || y != 300 {
+		t.Errorf("wrong cursor position: %d,%d", x, y)
+	}
+}